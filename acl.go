@@ -0,0 +1,228 @@
+package botx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-botx/botx/models"
+	"github.com/google/uuid"
+)
+
+// ACLRules matches command sender. AD logins are given as "login",
+// "DOMAIN\login" or "login@domain", all strings are compared case-insensitively.
+type ACLRules struct {
+	UserHUIDs []uuid.UUID `json:"user_huids,omitempty"`
+	ADLogins  []string    `json:"ad_logins,omitempty"`
+	ADDomains []string    `json:"ad_domains,omitempty"`
+	Emails    []string    `json:"emails,omitempty"`
+	ChatIds   []uuid.UUID `json:"chat_ids,omitempty"`
+}
+
+func (r *ACLRules) empty() bool {
+	return len(r.UserHUIDs) == 0 && len(r.ADLogins) == 0 && len(r.ADDomains) == 0 && len(r.Emails) == 0 && len(r.ChatIds) == 0
+}
+
+// ACL limits who can use the bot. A sender is allowed if Allow is empty or
+// matches, and Deny does not match. Denied senders see Reason as status message.
+type ACL struct {
+	Allow  ACLRules `json:"allow"`
+	Deny   ACLRules `json:"deny"`
+	Reason string   `json:"reason,omitempty"`
+}
+
+// LoadACLFile reads ACL from JSON file.
+func LoadACLFile(path string) (ACL, error) {
+	var acl ACL
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return acl, err
+	}
+	err = json.Unmarshal(data, &acl)
+	return acl, err
+}
+
+const defaultACLReason = "You are not allowed to use this bot"
+
+type aclSubject struct {
+	userHUID uuid.UUID
+	adLogin  string
+	adDomain string
+	chatId   uuid.UUID // uuid.Nil if unknown
+}
+
+// compiledACL is ACL with emails resolved to user HUIDs in background by
+// SetACL. Until emails are resolved, allow rules by email match nobody and
+// everyone is denied if there are deny rules by email.
+type compiledACL struct {
+	ACL
+	emails atomic.Pointer[aclEmails]
+}
+
+type aclEmails struct {
+	allow []uuid.UUID
+	deny  []uuid.UUID
+}
+
+func (a *compiledACL) check(s aclSubject) (allowed bool, reason string) {
+	reason = a.Reason
+	if reason == "" {
+		reason = defaultACLReason
+	}
+	var allowByEmails, denyByEmails []uuid.UUID
+	if emails := a.emails.Load(); emails != nil {
+		allowByEmails, denyByEmails = emails.allow, emails.deny
+	} else if len(a.Deny.Emails) > 0 {
+		// denied users are not known yet
+		return false, reason
+	}
+	if a.Deny.matches(s, denyByEmails, false) {
+		return false, reason
+	}
+	if a.Allow.empty() || a.Allow.matches(s, allowByEmails, true) {
+		return true, ""
+	}
+	return false, reason
+}
+
+// matches reports whether s matches any rule. Chat rules are skipped for
+// subjects without chat (status requests); if those are the only allow rules,
+// unknownChat is returned.
+func (r *ACLRules) matches(s aclSubject, huidsByEmails []uuid.UUID, unknownChat bool) bool {
+	if slices.Contains(r.UserHUIDs, s.userHUID) || slices.Contains(huidsByEmails, s.userHUID) {
+		return true
+	}
+	for _, domain := range r.ADDomains {
+		if s.adDomain != "" && strings.EqualFold(domain, s.adDomain) {
+			return true
+		}
+	}
+	for _, login := range r.ADLogins {
+		if s.adLogin != "" && matchADLogin(login, s.adLogin, s.adDomain) {
+			return true
+		}
+	}
+	if s.chatId == uuid.Nil {
+		return unknownChat && len(r.ChatIds) > 0 && len(r.UserHUIDs) == 0 && len(r.ADLogins) == 0 && len(r.ADDomains) == 0 && len(r.Emails) == 0
+	}
+	return slices.Contains(r.ChatIds, s.chatId)
+}
+
+func matchADLogin(rule string, login string, domain string) bool {
+	if d, l, ok := strings.Cut(rule, `\`); ok {
+		return strings.EqualFold(l, login) && strings.EqualFold(d, domain)
+	}
+	if l, d, ok := strings.Cut(rule, "@"); ok {
+		return strings.EqualFold(l, login) && strings.EqualFold(d, domain)
+	}
+	return strings.EqualFold(rule, login)
+}
+
+const (
+	aclResolveMinBackoff = time.Second
+	aclResolveMaxBackoff = 5 * time.Minute
+)
+
+// resolveACLEmailsLoop looks up HUIDs of users listed by email, retrying with
+// backoff until it succeeds, acl is replaced or the bot is shut down.
+func (b *Bot) resolveACLEmailsLoop(a *compiledACL) {
+	backoff := aclResolveMinBackoff
+	for {
+		allow, err := b.resolveACLEmails(a.Allow.Emails)
+		if err == nil {
+			var deny []uuid.UUID
+			if deny, err = b.resolveACLEmails(a.Deny.Emails); err == nil {
+				a.emails.Store(&aclEmails{allow: allow, deny: deny})
+				return
+			}
+		}
+		b.logger.Error("failed to resolve ACL emails", "error", err, "retry_in", backoff)
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if b.acl.Load() != a {
+			return
+		}
+		backoff = min(2*backoff, aclResolveMaxBackoff)
+	}
+}
+
+func (b *Bot) resolveACLEmails(emails []string) (huids []uuid.UUID, err error) {
+	if len(emails) == 0 {
+		return nil, nil
+	}
+	defer func() {
+		// token request failure panics
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	users, err := b.FindUsersByMails(emails)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		huids = append(huids, u.UserHUID)
+	}
+	return huids, nil
+}
+
+// SetACL replaces access control list, nil ACL allows everyone. Emails are
+// resolved in background, until then allow rules by email match nobody and,
+// if there are deny rules by email, everyone is denied.
+func (b *Bot) SetACL(acl *ACL) {
+	if acl == nil {
+		b.acl.Store(nil)
+		return
+	}
+	compiled := &compiledACL{ACL: *acl}
+	b.acl.Store(compiled)
+	if len(acl.Allow.Emails) > 0 || len(acl.Deny.Emails) > 0 {
+		go b.resolveACLEmailsLoop(compiled)
+	}
+}
+
+// ReloadACL rereads ACL file set by WithACLFile.
+func (b *Bot) ReloadACL() error {
+	if b.aclFile == "" {
+		return errors.New("ACL file is not configured")
+	}
+	acl, err := LoadACLFile(b.aclFile)
+	if err != nil {
+		return fmt.Errorf("failed to load ACL: %w", err)
+	}
+	b.SetACL(&acl)
+	return nil
+}
+
+func (b *Bot) checkCommandACL(req *models.CommandRequest) (bool, string) {
+	acl := b.acl.Load()
+	if acl == nil {
+		return true, ""
+	}
+	return acl.check(aclSubject{
+		userHUID: req.From.UserHUID,
+		adLogin:  req.From.ADLogin,
+		adDomain: req.From.ADDomain,
+		chatId:   req.From.GroupChatId,
+	})
+}
+
+func (b *Bot) checkStatusACL(req *models.StatusRequest) (bool, string) {
+	acl := b.acl.Load()
+	if acl == nil {
+		return true, ""
+	}
+	return acl.check(aclSubject{
+		userHUID: req.UserHUID,
+		adLogin:  req.ADLogin,
+		adDomain: req.ADDomain,
+	})
+}
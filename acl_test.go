@@ -0,0 +1,130 @@
+package botx
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestACLCheck(t *testing.T) {
+	user, other, chat := uuid.New(), uuid.New(), uuid.New()
+	tests := []struct {
+		name    string
+		acl     ACL
+		emails  *aclEmails
+		subject aclSubject
+		allowed bool
+	}{
+		{
+			name:    "empty allows everyone",
+			subject: aclSubject{userHUID: user, chatId: chat},
+			allowed: true,
+		},
+		{
+			name:    "allowed user",
+			acl:     ACL{Allow: ACLRules{UserHUIDs: []uuid.UUID{user}}},
+			subject: aclSubject{userHUID: user, chatId: chat},
+			allowed: true,
+		},
+		{
+			name:    "not allowed user",
+			acl:     ACL{Allow: ACLRules{UserHUIDs: []uuid.UUID{other}}},
+			subject: aclSubject{userHUID: user, chatId: chat},
+		},
+		{
+			name:    "deny wins over allow",
+			acl:     ACL{Allow: ACLRules{ADDomains: []string{"corp"}}, Deny: ACLRules{UserHUIDs: []uuid.UUID{user}}},
+			subject: aclSubject{userHUID: user, adDomain: "CORP", chatId: chat},
+		},
+		{
+			name:    "allowed domain",
+			acl:     ACL{Allow: ACLRules{ADDomains: []string{"corp"}}},
+			subject: aclSubject{userHUID: user, adLogin: "ivan", adDomain: "CORP", chatId: chat},
+			allowed: true,
+		},
+		{
+			name:    "allowed login with domain",
+			acl:     ACL{Allow: ACLRules{ADLogins: []string{`corp\ivan`}}},
+			subject: aclSubject{userHUID: user, adLogin: "Ivan", adDomain: "corp", chatId: chat},
+			allowed: true,
+		},
+		{
+			name:    "login of other domain",
+			acl:     ACL{Allow: ACLRules{ADLogins: []string{"ivan@corp"}}},
+			subject: aclSubject{userHUID: user, adLogin: "ivan", adDomain: "other", chatId: chat},
+		},
+		{
+			name:    "allowed chat",
+			acl:     ACL{Allow: ACLRules{ChatIds: []uuid.UUID{chat}}},
+			subject: aclSubject{userHUID: user, chatId: chat},
+			allowed: true,
+		},
+		{
+			name:    "other chat",
+			acl:     ACL{Allow: ACLRules{ChatIds: []uuid.UUID{chat}}},
+			subject: aclSubject{userHUID: user, chatId: uuid.New()},
+		},
+		{
+			name:    "status with chat only allow rules",
+			acl:     ACL{Allow: ACLRules{ChatIds: []uuid.UUID{chat}}},
+			subject: aclSubject{userHUID: user},
+			allowed: true,
+		},
+		{
+			name:    "status with chat only deny rules",
+			acl:     ACL{Deny: ACLRules{ChatIds: []uuid.UUID{chat}}},
+			subject: aclSubject{userHUID: user},
+			allowed: true,
+		},
+		{
+			name:    "status with chat and user allow rules",
+			acl:     ACL{Allow: ACLRules{ChatIds: []uuid.UUID{chat}, UserHUIDs: []uuid.UUID{other}}},
+			subject: aclSubject{userHUID: user},
+		},
+		{
+			name:    "allowed email",
+			acl:     ACL{Allow: ACLRules{Emails: []string{"ivan@corp"}}},
+			emails:  &aclEmails{allow: []uuid.UUID{user}},
+			subject: aclSubject{userHUID: user, chatId: chat},
+			allowed: true,
+		},
+		{
+			name:    "allowed email not resolved",
+			acl:     ACL{Allow: ACLRules{Emails: []string{"ivan@corp"}}},
+			subject: aclSubject{userHUID: user, chatId: chat},
+		},
+		{
+			name:    "denied email",
+			acl:     ACL{Deny: ACLRules{Emails: []string{"ivan@corp"}}},
+			emails:  &aclEmails{deny: []uuid.UUID{user}},
+			subject: aclSubject{userHUID: user, chatId: chat},
+		},
+		{
+			name:    "not denied email",
+			acl:     ACL{Deny: ACLRules{Emails: []string{"ivan@corp"}}},
+			emails:  &aclEmails{deny: []uuid.UUID{user}},
+			subject: aclSubject{userHUID: other, chatId: chat},
+			allowed: true,
+		},
+		{
+			name:    "denied email not resolved",
+			acl:     ACL{Deny: ACLRules{Emails: []string{"ivan@corp"}}},
+			subject: aclSubject{userHUID: other, chatId: chat},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &compiledACL{ACL: tt.acl}
+			if tt.emails != nil {
+				a.emails.Store(tt.emails)
+			}
+			allowed, reason := a.check(tt.subject)
+			if allowed != tt.allowed {
+				t.Fatalf("check() = %t, want %t", allowed, tt.allowed)
+			}
+			if !allowed && reason != defaultACLReason {
+				t.Errorf("reason = %q, want %q", reason, defaultACLReason)
+			}
+		})
+	}
+}
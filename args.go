@@ -0,0 +1,579 @@
+package botx
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-botx/botx/models"
+	"github.com/google/uuid"
+)
+
+// BindArgs parses req.Command.Body and stores arguments into struct pointed by dst.
+//
+// Fields are mapped with `botx` tag:
+//
+//	Who     models.User   `botx:"arg=0,required,name=user"`
+//	In      time.Duration `botx:"arg=1,default=1h"`
+//	Text    []string      `botx:"arg=2"` // rest of positional arguments
+//	Urgent  bool          `botx:"flag=urgent,help=notify immediately"`
+//
+// Leading "/command" token of the body is skipped. Returned error is *ArgsError
+// for any user input problem, its Message method gives localized text with usage.
+func BindArgs(req *models.CommandRequest, dst any) error {
+	spec, err := parseArgsSpec(dst)
+	if err != nil {
+		return err
+	}
+	tokens, err := SplitArgs(req.Command.Body)
+	if len(tokens) > 0 && strings.HasPrefix(tokens[0], "/") {
+		spec.command = tokens[0]
+		tokens = tokens[1:]
+	}
+	if err != nil {
+		return spec.newError(ArgsErrorSyntax, "", "", err)
+	}
+	return spec.bind(req, tokens, reflect.ValueOf(dst).Elem())
+}
+
+// ArgsUsage returns localized usage line for command with arguments described by dst.
+func ArgsUsage(command string, dst any, locale string) (string, error) {
+	spec, err := parseArgsSpec(dst)
+	if err != nil {
+		return "", err
+	}
+	spec.command = command
+	return spec.usage(locale), nil
+}
+
+// SplitArgs splits s into tokens the way shell does: whitespace separates tokens,
+// single quotes keep text as is, double quotes allow backslash escapes.
+func SplitArgs(s string) (tokens []string, err error) {
+	var (
+		cur     strings.Builder
+		inToken bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case quote != 0:
+			switch {
+			case r == '\\':
+				escaped = true
+			case r == closingQuote(quote):
+				quote = 0
+			default:
+				cur.WriteRune(r)
+			}
+		case r == '\\':
+			escaped = true
+			inToken = true
+		case closingQuote(r) != 0:
+			quote = r
+			inToken = true
+		case unicode.IsSpace(r):
+			if inToken {
+				tokens = append(tokens, cur.String())
+				cur.Reset()
+				inToken = false
+			}
+		default:
+			cur.WriteRune(r)
+			inToken = true
+		}
+	}
+	if quote != 0 {
+		return tokens, errUnterminatedQuote
+	}
+	if escaped {
+		return tokens, errUnfinishedEscape
+	}
+	if inToken {
+		tokens = append(tokens, cur.String())
+	}
+	return tokens, nil
+}
+
+// closingQuote returns pair for opening quote r, or zero if r is not a quote.
+// Typographic quotes are accepted as clients tend to replace plain ones.
+func closingQuote(r rune) rune {
+	switch r {
+	case '"', '\'':
+		return r
+	case '“':
+		return '”'
+	case '«':
+		return '»'
+	case '„':
+		return '“'
+	}
+	return 0
+}
+
+type ArgsErrorReason string
+
+const (
+	ArgsErrorSyntax     = ArgsErrorReason("syntax")
+	ArgsErrorMissing    = ArgsErrorReason("missing")
+	ArgsErrorInvalid    = ArgsErrorReason("invalid")
+	ArgsErrorUnknown    = ArgsErrorReason("unknown_flag")
+	ArgsErrorUnexpected = ArgsErrorReason("unexpected")
+)
+
+type ArgsError struct {
+	Reason ArgsErrorReason
+	Name   string
+	Value  string
+	Err    error
+
+	spec *argsSpec
+}
+
+func (e *ArgsError) Error() string {
+	return e.message(defaultLocale)
+}
+
+func (e *ArgsError) Unwrap() error {
+	return e.Err
+}
+
+// Message returns localized description of the error followed by usage line.
+func (e *ArgsError) Message(locale string) string {
+	return e.message(locale) + "\n" + e.spec.usage(locale)
+}
+
+func (e *ArgsError) message(locale string) string {
+	switch e.Reason {
+	case ArgsErrorMissing:
+		return fmt.Sprintf(argsMessages.missing.For(locale), e.Name)
+	case ArgsErrorInvalid:
+		return fmt.Sprintf(argsMessages.invalid.For(locale), e.Value, e.Name, localizedReason(e.Err, locale))
+	case ArgsErrorUnknown:
+		return fmt.Sprintf(argsMessages.unknown.For(locale), e.Name)
+	case ArgsErrorUnexpected:
+		return fmt.Sprintf(argsMessages.unexpected.For(locale), e.Value)
+	}
+	return fmt.Sprintf(argsMessages.syntax.For(locale), localizedReason(e.Err, locale))
+}
+
+var argsMessages = struct {
+	syntax, missing, invalid, unknown, unexpected, usage LocalizedString
+}{
+	syntax: LocalizedString{
+		"en": "Cannot parse command: %v.",
+		"ru": "Не удалось разобрать команду: %v.",
+	},
+	missing: LocalizedString{
+		"en": "Required argument %s is missing.",
+		"ru": "Не указан обязательный аргумент %s.",
+	},
+	invalid: LocalizedString{
+		"en": "Invalid value %q for %s: %v.",
+		"ru": "Недопустимое значение %q для %s: %v.",
+	},
+	unknown: LocalizedString{
+		"en": "Unknown option --%s.",
+		"ru": "Неизвестный параметр --%s.",
+	},
+	unexpected: LocalizedString{
+		"en": "Unexpected argument %q.",
+		"ru": "Лишний аргумент %q.",
+	},
+	usage: LocalizedString{
+		"en": "Usage: %s",
+		"ru": "Использование: %s",
+	},
+}
+
+type argSpec struct {
+	field    int
+	name     string
+	position int // -1 for flags
+	required bool
+	def      *string
+	help     string
+	variadic bool
+}
+
+type argsSpec struct {
+	command    string
+	positional []*argSpec
+	flags      map[string]*argSpec
+	flagNames  []string
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+	uuidType     = reflect.TypeOf(uuid.UUID{})
+	userType     = reflect.TypeOf(models.User{})
+	addressType  = reflect.TypeOf(mail.Address{})
+)
+
+func parseArgsSpec(dst any) (*argsSpec, error) {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("args destination must be a pointer to struct, got %T", dst)
+	}
+	t := v.Elem().Type()
+	spec := &argsSpec{flags: map[string]*argSpec{}}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("botx")
+		if !ok || tag == "-" {
+			continue
+		}
+		if !f.IsExported() {
+			return nil, fmt.Errorf("field %s with botx tag is not exported", f.Name)
+		}
+		as := &argSpec{field: i, position: -1, name: strings.ToLower(f.Name)}
+		isArg, isFlag := false, false
+		for _, part := range strings.Split(tag, ",") {
+			key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch key {
+			case "arg":
+				pos, err := strconv.Atoi(value)
+				if err != nil || pos < 0 {
+					return nil, fmt.Errorf("field %s: invalid arg position %q", f.Name, value)
+				}
+				as.position = pos
+				isArg = true
+			case "flag":
+				if value != "" {
+					as.name = value
+				}
+				isFlag = true
+			case "name":
+				as.name = value
+			case "required":
+				as.required = true
+			case "default":
+				as.def = &value
+			case "help":
+				as.help = value
+			case "":
+			default:
+				return nil, fmt.Errorf("field %s: unknown botx tag option %q", f.Name, key)
+			}
+		}
+		if isArg == isFlag {
+			return nil, fmt.Errorf("field %s: exactly one of arg or flag must be set", f.Name)
+		}
+		if isFlag {
+			spec.flags[as.name] = as
+			spec.flagNames = append(spec.flagNames, as.name)
+			continue
+		}
+		for len(spec.positional) <= as.position {
+			spec.positional = append(spec.positional, nil)
+		}
+		if spec.positional[as.position] != nil {
+			return nil, fmt.Errorf("field %s: duplicate arg position %d", f.Name, as.position)
+		}
+		spec.positional[as.position] = as
+	}
+	for i, as := range spec.positional {
+		if as == nil {
+			return nil, fmt.Errorf("no field for arg position %d", i)
+		}
+		as.variadic = t.Field(as.field).Type.Kind() == reflect.Slice
+		if as.variadic && i != len(spec.positional)-1 {
+			return nil, fmt.Errorf("field %s: only the last positional argument may be a slice", t.Field(as.field).Name)
+		}
+	}
+	return spec, nil
+}
+
+func (s *argsSpec) newError(reason ArgsErrorReason, name, value string, err error) *ArgsError {
+	return &ArgsError{Reason: reason, Name: name, Value: value, Err: err, spec: s}
+}
+
+func (s *argsSpec) bind(req *models.CommandRequest, tokens []string, dst reflect.Value) error {
+	var positional []string
+	seen := map[*argSpec]bool{}
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if tok == "--" {
+			positional = append(positional, tokens[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(tok, "--") || len(tok) == 2 {
+			positional = append(positional, tok)
+			continue
+		}
+		name, value, hasValue := strings.Cut(tok[2:], "=")
+		as, ok := s.flags[name]
+		if !ok {
+			return s.newError(ArgsErrorUnknown, name, "", nil)
+		}
+		field := dst.Field(as.field)
+		if !hasValue {
+			if field.Kind() == reflect.Bool {
+				value = "true"
+			} else if i+1 < len(tokens) {
+				i++
+				value = tokens[i]
+			} else {
+				return s.newError(ArgsErrorMissing, "--"+name, "", nil)
+			}
+		}
+		if err := setArgValue(req, field, value, seen[as]); err != nil {
+			return s.newError(ArgsErrorInvalid, "--"+name, value, err)
+		}
+		seen[as] = true
+	}
+
+	for i, as := range s.positional {
+		field := dst.Field(as.field)
+		if as.variadic {
+			for _, value := range positional[min(i, len(positional)):] {
+				if err := setArgValue(req, field, value, true); err != nil {
+					return s.newError(ArgsErrorInvalid, as.name, value, err)
+				}
+				seen[as] = true
+			}
+			positional = nil
+			break
+		}
+		if i >= len(positional) {
+			break
+		}
+		if err := setArgValue(req, field, positional[i], false); err != nil {
+			return s.newError(ArgsErrorInvalid, as.name, positional[i], err)
+		}
+		seen[as] = true
+	}
+	if len(positional) > len(s.positional) {
+		return s.newError(ArgsErrorUnexpected, "", positional[len(s.positional)], nil)
+	}
+
+	for _, as := range s.all() {
+		if seen[as] {
+			continue
+		}
+		name := as.name
+		if as.position < 0 {
+			name = "--" + name
+		}
+		if as.required {
+			return s.newError(ArgsErrorMissing, name, "", nil)
+		}
+		if as.def != nil {
+			if err := setArgValue(req, dst.Field(as.field), *as.def, false); err != nil {
+				return fmt.Errorf("invalid default value for %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *argsSpec) all() []*argSpec {
+	all := append([]*argSpec{}, s.positional...)
+	for _, name := range s.flagNames {
+		all = append(all, s.flags[name])
+	}
+	return all
+}
+
+func (s *argsSpec) usage(locale string) string {
+	parts := []string{}
+	if s.command != "" {
+		parts = append(parts, s.command)
+	}
+	for _, as := range s.positional {
+		name := as.name
+		if as.variadic {
+			name += "..."
+		}
+		if as.required {
+			parts = append(parts, "<"+name+">")
+		} else {
+			parts = append(parts, "["+name+"]")
+		}
+	}
+	for _, name := range s.flagNames {
+		parts = append(parts, "[--"+name+"]")
+	}
+	usage := fmt.Sprintf(argsMessages.usage.For(locale), strings.Join(parts, " "))
+	for _, as := range s.all() {
+		if as.help == "" {
+			continue
+		}
+		name := as.name
+		if as.position < 0 {
+			name = "--" + name
+		}
+		usage += "\n  " + name + " — " + as.help
+	}
+	return usage
+}
+
+func setArgValue(req *models.CommandRequest, field reflect.Value, value string, appendValue bool) error {
+	if field.Kind() == reflect.Slice {
+		elem := reflect.New(field.Type().Elem()).Elem()
+		if err := setArgValue(req, elem, value, false); err != nil {
+			return err
+		}
+		if !appendValue {
+			field.Set(reflect.MakeSlice(field.Type(), 0, 1))
+		}
+		field.Set(reflect.Append(field, elem))
+		return nil
+	}
+	if field.Kind() == reflect.Pointer {
+		ptr := reflect.New(field.Type().Elem())
+		if err := setArgValue(req, ptr.Elem(), value, false); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+
+	switch field.Type() {
+	case durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return errExpectedDuration
+		}
+		field.SetInt(int64(d))
+		return nil
+	case timeType:
+		for _, layout := range []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02", "02.01.2006 15:04", "02.01.2006"} {
+			if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+				field.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return errExpectedDate
+	case uuidType:
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return errExpectedUUID
+		}
+		field.Set(reflect.ValueOf(id))
+		return nil
+	case addressType:
+		addr, err := mail.ParseAddress(value)
+		if err != nil {
+			return errExpectedEmail
+		}
+		field.Set(reflect.ValueOf(*addr))
+		return nil
+	case userType:
+		user, err := mentionedUser(req, value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(user))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return errExpectedBool
+		}
+		field.SetBool(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return errExpectedInt
+		}
+		field.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return errExpectedUint
+		}
+		field.SetUint(v)
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), field.Type().Bits())
+		if err != nil {
+			return errExpectedNumber
+		}
+		field.SetFloat(v)
+	default:
+		return fmt.Errorf("unsupported argument type %s", field.Type())
+	}
+	return nil
+}
+
+// valueError is a reason of value rejection, it is put into localized
+// messages in the locale of the user.
+type valueError struct {
+	reason LocalizedString
+}
+
+func (e *valueError) Error() string {
+	return e.reason.For(defaultLocale)
+}
+
+// localizedReason returns text of err in locale if err is valueError.
+func localizedReason(err error, locale string) string {
+	var ve *valueError
+	if errors.As(err, &ve) {
+		return ve.reason.For(locale)
+	}
+	return err.Error()
+}
+
+var (
+	errUnterminatedQuote = &valueError{LocalizedString{"en": "unterminated quoted string", "ru": "не закрыта кавычка"}}
+	errUnfinishedEscape  = &valueError{LocalizedString{"en": "unfinished escape sequence", "ru": "не завершена escape-последовательность"}}
+	errExpectedDuration  = &valueError{LocalizedString{"en": "expected duration like 1h30m", "ru": "ожидается длительность вида 1h30m"}}
+	errExpectedDate      = &valueError{LocalizedString{"en": "expected date like 2006-01-02", "ru": "ожидается дата вида 2006-01-02"}}
+	errExpectedUUID      = &valueError{LocalizedString{"en": "expected UUID", "ru": "ожидается UUID"}}
+	errExpectedEmail     = &valueError{LocalizedString{"en": "expected email address", "ru": "ожидается адрес электронной почты"}}
+	errExpectedBool      = &valueError{LocalizedString{"en": "expected true or false", "ru": "ожидается true или false"}}
+	errExpectedInt       = &valueError{LocalizedString{"en": "expected integer number", "ru": "ожидается целое число"}}
+	errExpectedUint      = &valueError{LocalizedString{"en": "expected non-negative integer number", "ru": "ожидается неотрицательное целое число"}}
+	errExpectedNumber    = &valueError{LocalizedString{"en": "expected number", "ru": "ожидается число"}}
+	errExpectedMention   = &valueError{LocalizedString{"en": "expected user mention", "ru": "ожидается упоминание пользователя"}}
+	errMentionNotUser    = &valueError{LocalizedString{"en": "mention does not refer to a user", "ru": "упоминание не относится к пользователю"}}
+	errUnknownMention    = &valueError{LocalizedString{"en": "unknown mention", "ru": "неизвестное упоминание"}}
+)
+
+const mentionPrefix, mentionSuffix = "@{mention:", "}"
+
+// mentionedUser resolves "@{mention:<id>}" token of the body (or plain user HUID)
+// to the user referenced by corresponding mention entity of the request.
+func mentionedUser(req *models.CommandRequest, value string) (models.User, error) {
+	if id, err := uuid.Parse(value); err == nil {
+		return models.User{UserHUID: id}, nil
+	}
+	if !strings.HasPrefix(value, mentionPrefix) || !strings.HasSuffix(value, mentionSuffix) {
+		return models.User{}, errExpectedMention
+	}
+	mentionId, err := uuid.Parse(value[len(mentionPrefix) : len(value)-len(mentionSuffix)])
+	if err != nil {
+		return models.User{}, errExpectedMention
+	}
+	for _, m := range req.Mentions() {
+		if m.MentionId != mentionId {
+			continue
+		}
+		if !m.IsUser() {
+			return models.User{}, errMentionNotUser
+		}
+		return models.User{UserHUID: m.MentionData.UserHUID, Name: m.MentionData.Name}, nil
+	}
+	return models.User{}, errUnknownMention
+}
@@ -0,0 +1,159 @@
+package botx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-botx/botx/models"
+	"github.com/google/uuid"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{in: "", want: nil},
+		{in: "  /remind  me\ttomorrow \n", want: []string{"/remind", "me", "tomorrow"}},
+		{in: `a "b c" d`, want: []string{"a", "b c", "d"}},
+		{in: `'it''s' "say \"hi\""`, want: []string{"its", `say "hi"`}},
+		{in: `'a\b' "a\\b" a\ b`, want: []string{`a\b`, `a\b`, "a b"}},
+		{in: `"" ''`, want: []string{"", ""}},
+		{in: `“smart quotes” «ёлки палки» „low“`, want: []string{"smart quotes", "ёлки палки", "low"}},
+		{in: `x"y z"w`, want: []string{"xy zw"}},
+		{in: `"unterminated`, wantErr: true},
+		{in: `trailing\`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := SplitArgs(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("SplitArgs(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitArgs(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+type remindArgs struct {
+	Who    models.User   `botx:"arg=0,required,name=user"`
+	In     time.Duration `botx:"arg=1,default=1h"`
+	Text   []string      `botx:"arg=2"`
+	Urgent bool          `botx:"flag=urgent,help=notify immediately"`
+	Tags   []string      `botx:"flag=tag"`
+	Repeat *int          `botx:"flag=repeat"`
+}
+
+func mentionRequest(body string, mentionId uuid.UUID, user models.User) *models.CommandRequest {
+	req := newTestCommand(body)
+	entity, _ := json.Marshal(map[string]any{
+		"type": "mention",
+		"data": map[string]any{
+			"mention_type": "user",
+			"mention_id":   mentionId,
+			"mention_data": map[string]any{"user_huid": user.UserHUID, "name": user.Name},
+		},
+	})
+	req.Entities = []json.RawMessage{entity}
+	return req
+}
+
+func TestBindArgs(t *testing.T) {
+	mentionId, user := uuid.New(), models.User{UserHUID: uuid.New(), Name: "Bob"}
+	mention := "@{mention:" + mentionId.String() + "}"
+
+	var args remindArgs
+	req := mentionRequest(fmt.Sprintf(`/remind %s 30m --urgent --tag a --tag=b --repeat 3 "buy milk" now`, mention), mentionId, user)
+	if err := BindArgs(req, &args); err != nil {
+		t.Fatal(err)
+	}
+	want := remindArgs{Who: user, In: 30 * time.Minute, Text: []string{"buy milk", "now"}, Urgent: true, Tags: []string{"a", "b"}}
+	repeat := 3
+	want.Repeat = &repeat
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("BindArgs() = %+v, want %+v", args, want)
+	}
+
+	args = remindArgs{}
+	if err := BindArgs(newTestCommand("/remind "+user.UserHUID.String()), &args); err != nil {
+		t.Fatal(err)
+	}
+	if args.Who.UserHUID != user.UserHUID || args.In != time.Hour {
+		t.Errorf("BindArgs() with default = %+v", args)
+	}
+
+	args = remindArgs{}
+	if err := BindArgs(newTestCommand("/remind "+user.UserHUID.String()+" 2h -- --urgent"), &args); err != nil {
+		t.Fatal(err)
+	}
+	if args.Urgent || !reflect.DeepEqual(args.Text, []string{"--urgent"}) {
+		t.Errorf("BindArgs() after -- = %+v", args)
+	}
+}
+
+func TestBindArgsErrors(t *testing.T) {
+	mentionId, user := uuid.New(), models.User{UserHUID: uuid.New(), Name: "Bob"}
+	mention := "@{mention:" + mentionId.String() + "}"
+	tests := []struct {
+		body   string
+		reason ArgsErrorReason
+		name   string
+	}{
+		{body: "/remind", reason: ArgsErrorMissing, name: "user"},
+		{body: `/remind "oops`, reason: ArgsErrorSyntax},
+		{body: "/remind " + mention + " soon", reason: ArgsErrorInvalid, name: "In"},
+		{body: "/remind " + mention + " 1h --loud", reason: ArgsErrorUnknown, name: "loud"},
+		{body: "/remind " + mention + " --repeat", reason: ArgsErrorMissing, name: "--repeat"},
+		{body: "/remind @{mention:" + uuid.NewString() + "}", reason: ArgsErrorInvalid, name: "user"},
+	}
+	for _, tt := range tests {
+		var args remindArgs
+		err := BindArgs(mentionRequest(tt.body, mentionId, user), &args)
+		var argsErr *ArgsError
+		if !errors.As(err, &argsErr) {
+			t.Errorf("BindArgs(%q) = %v, want *ArgsError", tt.body, err)
+			continue
+		}
+		if argsErr.Reason != tt.reason || !strings.EqualFold(argsErr.Name, tt.name) {
+			t.Errorf("BindArgs(%q) = %s %q, want %s %q", tt.body, argsErr.Reason, argsErr.Name, tt.reason, tt.name)
+		}
+		if msg := argsErr.Message("ru"); !strings.Contains(msg, "/remind") {
+			t.Errorf("BindArgs(%q) message has no usage: %q", tt.body, msg)
+		}
+	}
+}
+
+func TestBindArgsUnexpected(t *testing.T) {
+	var args struct {
+		N int `botx:"arg=0"`
+	}
+	err := BindArgs(newTestCommand("/count 1 2"), &args)
+	var argsErr *ArgsError
+	if !errors.As(err, &argsErr) || argsErr.Reason != ArgsErrorUnexpected || argsErr.Value != "2" {
+		t.Fatalf("BindArgs() = %v, want unexpected 2", err)
+	}
+}
+
+func TestBindArgsLocalizedReason(t *testing.T) {
+	var args struct {
+		N int `botx:"arg=0"`
+	}
+	err := BindArgs(newTestCommand("/count many"), &args)
+	var argsErr *ArgsError
+	if !errors.As(err, &argsErr) || argsErr.Reason != ArgsErrorInvalid {
+		t.Fatalf("BindArgs() = %v, want invalid", err)
+	}
+	if msg := argsErr.Message("ru"); !strings.Contains(msg, "ожидается целое число") || strings.Contains(msg, "expected") {
+		t.Errorf("russian message = %q", msg)
+	}
+	if msg := argsErr.Message("en"); !strings.Contains(msg, "expected integer number") {
+		t.Errorf("english message = %q", msg)
+	}
+}
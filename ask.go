@@ -0,0 +1,170 @@
+package botx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/go-botx/botx/models"
+	"github.com/google/uuid"
+)
+
+var ErrAskReplaced = errors.New("question is replaced by a newer one")
+
+// AskAnswer is a reply to a question sent by Bot.Ask.
+type AskAnswer struct {
+	Request *models.CommandRequest
+	// Clicked is true when answer is a button click, Command and Data are
+	// those of the clicked button then.
+	Clicked bool
+	Command string
+	Data    json.RawMessage
+}
+
+// Text returns text of answer message or clicked button command.
+func (a *AskAnswer) Text() string {
+	return a.Request.Command.Body
+}
+
+type askKey struct {
+	chatId   uuid.UUID
+	userHUID uuid.UUID
+}
+
+type askWaiter struct {
+	id     uuid.UUID
+	ch     chan *AskAnswer
+	secret bool
+}
+
+// askButtonData wraps data of buttons of a question to correlate clicks.
+type askButtonData struct {
+	Ask  uuid.UUID `json:"botx_ask"`
+	Data any       `json:"data,omitempty"`
+}
+
+// askClickData is askButtonData of clicked button, data is kept as sent.
+type askClickData struct {
+	Ask  uuid.UUID       `json:"botx_ask"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// askManager delivers messages of users to goroutines waiting for their answer,
+// the same way ncbManager delivers notification callbacks.
+type askManager struct {
+	mutex   sync.Mutex
+	waiters map[askKey]*askWaiter
+}
+
+func newAskManager() *askManager {
+	return &askManager{waiters: make(map[askKey]*askWaiter)}
+}
+
+func (am *askManager) register(key askKey, secret bool) *askWaiter {
+	w := &askWaiter{id: uuid.New(), ch: make(chan *AskAnswer, 1), secret: secret}
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+	if prev, exists := am.waiters[key]; exists {
+		close(prev.ch)
+	}
+	am.waiters[key] = w
+	return w
+}
+
+func (am *askManager) unregister(key askKey, w *askWaiter) {
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+	if am.waiters[key] == w {
+		delete(am.waiters, key)
+	}
+}
+
+// deliver passes req to the waiter of its sender. Returns true if req is
+// consumed: delivered, or a click on a button of a question nobody waits for.
+// secret is true if req is an answer to AskSecret.
+func (am *askManager) deliver(req *models.CommandRequest) (consumed bool, secret bool) {
+	var data askClickData
+	clicked := len(req.Command.Data) > 0 && json.Unmarshal(req.Command.Data, &data) == nil && data.Ask != uuid.Nil
+
+	key := askKey{chatId: req.From.GroupChatId, userHUID: req.From.UserHUID}
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+	w, exists := am.waiters[key]
+	if !exists || (clicked && data.Ask != w.id) {
+		return clicked, false
+	}
+	answer := &AskAnswer{Request: req}
+	if clicked {
+		answer.Clicked = true
+		answer.Command = req.Command.Body
+		answer.Data = data.Data
+		if len(answer.Data) == 0 {
+			answer.Data = json.RawMessage("null")
+		}
+	}
+	w.ch <- answer
+	delete(am.waiters, key)
+	return true, w.secret
+}
+
+// Ask sends question to the chat and waits until user answers with a message
+// or clicks one of the buttons, or ctx is done. Messages of the user in the
+// chat are not passed to handlers while the question waits.
+func (b *Bot) Ask(ctx context.Context, chatId uuid.UUID, userHUID uuid.UUID, question string, buttons ...models.NDButton) (*AskAnswer, error) {
+	var options []models.NDRequestOption
+	if len(buttons) > 0 {
+		options = append(options, models.WithNDBubbleRow(buttons...))
+	}
+	message, err := models.NewNDRequest(chatId, question, options...)
+	if err != nil {
+		return nil, err
+	}
+	return b.AskMessage(ctx, userHUID, message)
+}
+
+// AskMessage is like Ask for a prepared message.
+func (b *Bot) AskMessage(ctx context.Context, userHUID uuid.UUID, message *models.NDRequest) (*AskAnswer, error) {
+	answer, _, err := b.askMessage(ctx, userHUID, message, false)
+	return answer, err
+}
+
+func (b *Bot) askMessage(ctx context.Context, userHUID uuid.UUID, message *models.NDRequest, secret bool) (answer *AskAnswer, syncId uuid.UUID, err error) {
+	if b.fiberApp == nil {
+		return nil, uuid.Nil, errors.New("bot is not configured to handle callbacks")
+	}
+	key := askKey{chatId: message.ChatId, userHUID: userHUID}
+	w := b.askManager.register(key, secret)
+	defer b.askManager.unregister(key, w)
+
+	// buttons are wrapped for this question, keep caller's message reusable
+	wrapped := *message
+	wrapped.Notification.Bubble = wrapAskButtons(message.Notification.Bubble, w.id)
+	wrapped.Notification.Keyboard = wrapAskButtons(message.Notification.Keyboard, w.id)
+	syncId, err = b.SendMessageAsync(&wrapped)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	select {
+	case answer, ok := <-w.ch:
+		if !ok {
+			return nil, syncId, ErrAskReplaced
+		}
+		return answer, syncId, nil
+	case <-ctx.Done():
+		return nil, syncId, ctx.Err()
+	}
+}
+
+func wrapAskButtons(rows models.NDButtons, id uuid.UUID) models.NDButtons {
+	rows = copyButtons(rows)
+	for _, row := range rows {
+		for i := range row {
+			if row[i].Command != "" {
+				row[i].Data = askButtonData{Ask: id, Data: row[i].Data}
+			}
+		}
+	}
+	return rows
+}
@@ -0,0 +1,35 @@
+package botx
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/go-botx/botx/models"
+	"github.com/google/uuid"
+)
+
+func TestAskDeliverKeepsButtonData(t *testing.T) {
+	am := newAskManager()
+	req := newTestCommand("/pick")
+	key := askKey{chatId: req.From.GroupChatId, userHUID: req.From.UserHUID}
+	w := am.register(key, false)
+	req.Command.Data = json.RawMessage(`{"botx_ask":"` + w.id.String() + `","data":{"id":9007199254740993}}`)
+	if consumed, _ := am.deliver(req); !consumed {
+		t.Fatal("click is not delivered")
+	}
+	answer := <-w.ch
+	if !answer.Clicked || string(answer.Data) != `{"id":9007199254740993}` {
+		t.Errorf("answer data = %s, want {\"id\":9007199254740993}", answer.Data)
+	}
+}
+
+func TestWrapAskButtonsKeepsButtons(t *testing.T) {
+	rows := models.NDButtons{{models.NewCommandButton("Yes", "/yes", 1)}}
+	wrapped := wrapAskButtons(rows, uuid.New())
+	if data := rows[0][0].Data; data != 1 {
+		t.Errorf("caller's button data changed to %v", data)
+	}
+	if _, ok := wrapped[0][0].Data.(askButtonData); !ok {
+		t.Errorf("wrapped button data = %v, want askButtonData", wrapped[0][0].Data)
+	}
+}
@@ -1,10 +1,14 @@
 package botx
 
 import (
+	"context"
 	"errors"
 	"io"
+	"log/slog"
+	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-botx/botx/models"
@@ -17,6 +21,10 @@ import (
 type StatusCallbackHandler func(b *Bot, req *models.StatusRequest) *models.StatusResponse
 type CommandCallbackHandler func(b *Bot, req *models.CommandRequest)
 
+// CommandCallbackContextHandler is a CommandCallbackHandler receiving context
+// that is cancelled when the bot is shut down.
+type CommandCallbackContextHandler func(ctx context.Context, b *Bot, req *models.CommandRequest)
+
 type Bot struct {
 	account          models.Credentials
 	tokenSig         string
@@ -34,16 +42,68 @@ type Bot struct {
 	debugHTTPService             bool
 	debugHTTPServiceLoggerConfig logger.Config
 
+	// file service
+	httpClient       *http.Client
+	asyncFileMaxSize int64
+
 	// chache
 	userIdChatIdCache *kvCache[uuid.UUID, uuid.UUID]
 	emailUserIdCache  *kvCache[string, uuid.UUID]
 
 	// sync callback manager
 	ncbManager *ncbManager
+	askManager *askManager
 
 	// handlers
 	statusCallbackHandler  StatusCallbackHandler
-	commandCallbackHandler CommandCallbackHandler
+	commandCallbackHandler CommandCallbackContextHandler
+	router                 *Router
+
+	logger *slog.Logger
+
+	// command workers
+	commandWorkers   int
+	commandQueueSize int
+	commandQueue     chan func()
+	commandOrder     *orderedCommands
+	queueFullMessage string
+
+	// deduplication
+	dedupStore           DedupStore
+	dedupTTL             time.Duration
+	duplicateCommandHook DuplicateCommandHook
+
+	wal *commandWAL
+
+	// access control
+	acl     atomic.Pointer[compiledACL]
+	aclFile string
+
+	rateLimiter   *rateLimiter
+	buttonSigning *ButtonSigning
+
+	// button data storage
+	buttonStore    ButtonDataStore
+	buttonStoreTTL time.Duration
+	oneTime        *oneTimeButtons
+
+	// error replies
+	userErrorReply     LocalizedString
+	internalErrorReply LocalizedString
+
+	// timeouts
+	commandTimeout      time.Duration
+	commandTimeoutReply LocalizedString
+	watchdogThreshold   time.Duration
+	slowCommandHook     SlowCommandHook
+
+	// lifecycle
+	ctx          context.Context
+	cancel       context.CancelFunc
+	handlersWG   sync.WaitGroup
+	shutdownMtx  sync.RWMutex
+	shuttingDown bool
+	shutdownOnce sync.Once
 
 	// jwt options
 	jwtLeeway time.Duration
@@ -54,6 +114,8 @@ func New(botCredentials string, options ...Option) (bot *Bot, err error) {
 	return new(botCredentials, options...)
 }
 
+// FiberApp returns HTTP service handling BotX callbacks. The first call also
+// replays commands left unfinished in the WAL, see WithCommandWAL.
 func (b *Bot) FiberApp() *fiber.App {
 	b.initFiberApp()
 	return b.fiberApp
@@ -142,13 +204,58 @@ func (b *Bot) CreateChatWithUser(user models.User) (chatId uuid.UUID, err error)
 }
 
 func (b *Bot) SendMessageAsync(message *models.NDRequest) (syncId uuid.UUID, err error) {
+	// buttons are encoded for the chat, keep caller's message reusable
+	encoded := *message
+	encoded.Notification.Bubble = copyButtons(message.Notification.Bubble)
+	encoded.Notification.Keyboard = copyButtons(message.Notification.Keyboard)
+	message = &encoded
+	if err = b.encodeButtons(b.ctx, message.ChatId, message.Notification.Bubble, message.Notification.Keyboard); err != nil {
+		return uuid.Nil, err
+	}
 	resp, err := message.GetResponse(b.callApi)
 	if err != nil {
 		return uuid.Nil, err
 	}
+	b.oneTime.remember(resp.SyncId, message)
 	return resp.SyncId, err
 }
 
+// EditMessage changes message sent by the bot to the chat.
+func (b *Bot) EditMessage(chatId uuid.UUID, syncId uuid.UUID, options ...models.EditEventOption) error {
+	req, err := models.NewEditEventRequest(syncId, options...)
+	if err != nil {
+		return err
+	}
+	var bubble, keyboard models.NDButtons
+	if req.Payload.Bubble != nil {
+		bubble = copyButtons(*req.Payload.Bubble)
+		req.Payload.Bubble = &bubble
+	}
+	if req.Payload.Keyboard != nil {
+		keyboard = copyButtons(*req.Payload.Keyboard)
+		req.Payload.Keyboard = &keyboard
+	}
+	if err = b.encodeButtons(b.ctx, chatId, bubble, keyboard); err != nil {
+		return err
+	}
+	_, err = req.GetResponse(b.callApi)
+	return err
+}
+
+func (b *Bot) DeleteMessage(syncId uuid.UUID) error {
+	_, err := models.NewDeleteEventRequest(syncId).GetResponse(b.callApi)
+	return err
+}
+
+// Reply sends message to the chat req came from.
+func (b *Bot) Reply(req *models.CommandRequest, body string, options ...models.NDRequestOption) (syncId uuid.UUID, err error) {
+	message, err := models.NewNDRequest(req.From.GroupChatId, body, options...)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return b.SendMessageAsync(message)
+}
+
 func (b *Bot) SendMessageSync(message *models.NDRequest) (ncbr *models.NotificationCallbackRequest, err error) {
 	if b.fiberApp == nil {
 		return nil, errors.New("bot is not configured to handle callbacks")
@@ -160,3 +267,51 @@ func (b *Bot) SendMessageSync(message *models.NDRequest) (ncbr *models.Notificat
 	resp, err := b.ncbManager.awaitCallback(syncId)
 	return resp, err
 }
+
+// Shutdown stops accepting commands, waits for running command handlers until
+// ctx is done, then cancels handlers context and stops the HTTP service.
+// Returns ctx error if handlers did not finish in time. Only the first call has
+// effect, later calls return nil.
+func (b *Bot) Shutdown(ctx context.Context) (err error) {
+	b.shutdownOnce.Do(func() {
+		err = b.shutdown(ctx)
+	})
+	return err
+}
+
+func (b *Bot) shutdown(ctx context.Context) error {
+	b.shutdownMtx.Lock()
+	b.shuttingDown = true
+	b.shutdownMtx.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		b.handlersWG.Wait()
+		close(drained)
+	}()
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	b.cancel()
+	// nothing is submitted after shuttingDown is set, workers skip queued
+	// commands leaving them pending in WAL and exit
+	if b.commandQueue != nil {
+		close(b.commandQueue)
+	}
+	b.ncbManager.stop()
+	if b.wal != nil {
+		if errW := b.wal.close(); errW != nil && err == nil {
+			err = errW
+		}
+	}
+
+	if b.fiberApp != nil {
+		if errF := b.fiberApp.ShutdownWithContext(ctx); errF != nil && err == nil {
+			err = errF
+		}
+	}
+	return err
+}
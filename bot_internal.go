@@ -1,11 +1,14 @@
 package botx
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"strings"
 	"time"
 
@@ -34,8 +37,17 @@ func new(botCredentials string, options ...Option) (bot *Bot, err error) {
 		debugHTTPClient:        false,
 		debugHTTPClientWriter:  nil,
 		debugHTTPService:       false,
+		httpClient:             &http.Client{},
+		logger:                 slog.Default(),
+		queueFullMessage:       "Bot is overloaded, please try again later",
 		jwtLeeway:              time.Duration(time.Minute * 5),
 	}
+	bot.ctx, bot.cancel = context.WithCancel(context.Background())
+	bot.router = newRouter()
+	bot.askManager = newAskManager()
+	bot.oneTime = newOneTimeButtons()
+	bot.userErrorReply = defaultUserErrorReply
+	bot.internalErrorReply = defaultInternalErrorReply
 
 	bot.initTokenSignature()
 	bot.initJWTValidatingKey()
@@ -47,6 +59,11 @@ func new(botCredentials string, options ...Option) (bot *Bot, err error) {
 		}
 	}
 
+	if bot.commandWorkers > 0 {
+		bot.commandQueue = make(chan func(), bot.commandQueueSize)
+		bot.startCommandWorkers(bot.commandWorkers)
+	}
+
 	return
 }
 
@@ -73,6 +90,8 @@ func (b *Bot) initFiberApp() {
 	b.fiberApp.Get("/status", b.handleStatusCallback)
 	b.fiberApp.Post("/command", b.handleCommandCallback)
 	b.fiberApp.Post("/notification/callback", b.handleNotificationCallback)
+
+	b.replayCommands()
 }
 
 func (b *Bot) initTokenSignature() {
@@ -142,6 +161,13 @@ func (b *Bot) callApi(req models.ClientRequest) (statusCode int, resp []byte, er
 	return statusCode, body, nil
 }
 
+// reply is Reply logging failure.
+func (b *Bot) reply(req *models.CommandRequest, body string, options ...models.NDRequestOption) {
+	if _, err := b.Reply(req, body, options...); err != nil {
+		b.logger.Error("failed to send reply", "sync_id", req.SyncId, "error", err)
+	}
+}
+
 func (b *Bot) handleNotificationCallback(c *fiber.Ctx) error {
 	var ncbr models.NotificationCallbackRequest
 	err := c.BodyParser(&ncbr)
@@ -168,6 +194,9 @@ func (b *Bot) handleStatusCallback(c *fiber.Ctx) error {
 	if response == nil {
 		response = models.NewStatusResponse(false, "")
 	}
+	if allowed, reason := b.checkStatusACL(&statusReq); !allowed {
+		response = models.NewStatusResponse(false, reason)
+	}
 	return c.Status(fiber.StatusOK).JSON(&response, fiber.MIMEApplicationJSONCharsetUTF8)
 }
 
@@ -181,17 +210,62 @@ func (b *Bot) handleCommandCallback(c *fiber.Ctx) error {
 		return errors.New("command requested for different bot id")
 	}
 	var response *models.CommandResponse = nil
-	if b.commandCallbackHandler != nil {
-		response = models.CommandResponseSuccess()
-		dataReady := make(chan bool)
-		go func() {
-			var b = b
-			var commandReq = commandReq
-			dataReady <- true
-			b.commandCallbackHandler(b, &commandReq)
-		}()
-		<-dataReady
+	if allowed, reason := b.checkCommandACL(&commandReq); !allowed {
+		response = models.CommandResponseFailure(errors.New(reason))
+		return c.Status(response.StatusCode).JSON(response)
+	}
+	if b.isDuplicateCommand(c.UserContext(), &commandReq) {
+		return c.Status(fiber.StatusAccepted).JSON(models.CommandResponseSuccess())
+	}
+	oneTime, err := b.decodeCommandData(c.UserContext(), &commandReq)
+	var acceptClick, releaseClick func()
+	if err == nil {
+		acceptClick, releaseClick, err = b.claimOneTimeButton(c.UserContext(), &commandReq, oneTime)
+	}
+	if err != nil {
+		b.rejectButton(b.ctx, &commandReq, err)
+		return c.Status(fiber.StatusAccepted).JSON(models.CommandResponseSuccess())
+	}
+	if delivered, secret := b.askManager.deliver(&commandReq); delivered {
+		acceptClick()
+		if secret {
+			// keep secret out of HTTP service debug log
+			c.Request().ResetBody()
+		}
+		return c.Status(fiber.StatusAccepted).JSON(models.CommandResponseSuccess())
+	}
+	if b.commandCallbackHandler != nil || b.router.hasRoutes() {
+		if b.isRateLimited(&commandReq) {
+			releaseClick()
+			return c.Status(fiber.StatusAccepted).JSON(models.CommandResponseSuccess())
+		}
+		if b.wal != nil {
+			if err = b.wal.accept(&commandReq); err != nil {
+				b.logger.Error("failed to write command to WAL", "sync_id", commandReq.SyncId, "error", err)
+				err = errCommandNotPersisted
+			}
+		}
+		if err == nil {
+			err = b.submitCommand(&commandReq)
+		}
+		switch err {
+		case nil:
+			response = models.CommandResponseSuccess()
+		case errCommandQueueFull:
+			b.logger.Warn("command rejected, queue is full", "sync_id", commandReq.SyncId)
+			response = models.CommandResponseFailure(errors.New(b.queueFullMessage))
+		default:
+			response = models.CommandResponseFailure(err)
+		}
+		if err != nil {
+			b.commandDone(&commandReq)
+			b.forgetCommand(c.UserContext(), &commandReq)
+			releaseClick()
+		} else {
+			acceptClick()
+		}
 	} else {
+		releaseClick()
 		response = models.CommandResponseFailure(errors.New("callback not implemented"))
 	}
 	return c.Status(response.StatusCode).JSON(response)
@@ -0,0 +1,61 @@
+package botx
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-botx/botx/models"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// newTestBot returns bot with unreachable API and a token set, so nothing is
+// requested until a message is actually sent.
+func newTestBot(t *testing.T, options ...Option) *Bot {
+	t.Helper()
+	options = append([]Option{WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))}, options...)
+	b, err := New("127.0.0.1:1@secret@"+uuid.NewString(), options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.tokenRWMtx.Lock()
+	b.token = "test"
+	b.tokenRWMtx.Unlock()
+	return b
+}
+
+func newTestCommand(body string) *models.CommandRequest {
+	req := &models.CommandRequest{SyncId: uuid.New(), SourceSyncId: uuid.New()}
+	req.Command.Body = body
+	req.From.GroupChatId = uuid.New()
+	req.From.UserHUID = uuid.New()
+	return req
+}
+
+// postCommand sends req to command callback of b, returns HTTP status.
+func postCommand(t *testing.T, b *Bot, req *models.CommandRequest) int {
+	t.Helper()
+	req.BotId = b.Id()
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Audience: jwt.ClaimStrings{b.Id().String()},
+	}).SignedString(b.jwtValidatingKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest("POST", "/command", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Authorization", "Bearer "+token)
+	resp, err := b.FiberApp().Test(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode
+}
@@ -0,0 +1,61 @@
+package botx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-botx/botx/models"
+)
+
+// CommandRouter is implemented by Bot and Router.
+type CommandRouter interface {
+	Handle(command string, handler CommandCallbackContextHandler)
+}
+
+// ButtonHandler handles click on a button created by models.NewCommandButton
+// with data of type T.
+type ButtonHandler[T any] func(ctx context.Context, b *Bot, req *models.CommandRequest, data T)
+
+// Validator is implemented by button data types checking their content.
+type Validator interface {
+	Validate() error
+}
+
+var invalidButtonDataReply = LocalizedString{
+	"en": "This button carries invalid data.",
+	"ru": "Кнопка содержит неверные данные.",
+}
+
+// OnButton registers handler for command of buttons carrying data of type T.
+// Data is decoded strictly and validated if T implements Validator, clicks with
+// mismatching data are answered with an error reply.
+func OnButton[T any](r CommandRouter, command string, handler ButtonHandler[T]) {
+	r.Handle(command, func(ctx context.Context, b *Bot, req *models.CommandRequest) {
+		data, err := decodeButtonData[T](req.Command.Data)
+		if err != nil {
+			b.logger.Warn("invalid button data", "sync_id", req.SyncId, "command", CommandName(req), "error", err)
+			b.replyError(ctx, req, NewLocalizedUserError(invalidButtonDataReply))
+			return
+		}
+		handler(ctx, b, req, data)
+	})
+}
+
+func decodeButtonData[T any](raw json.RawMessage) (data T, err error) {
+	if len(raw) == 0 {
+		return data, fmt.Errorf("no data")
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(&data); err != nil {
+		return data, err
+	}
+	if v, ok := any(data).(Validator); ok {
+		err = v.Validate()
+	} else if v, ok := any(&data).(Validator); ok {
+		err = v.Validate()
+	}
+	return data, err
+}
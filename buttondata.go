@@ -0,0 +1,307 @@
+package botx
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-botx/botx/models"
+	"github.com/google/uuid"
+)
+
+var (
+	errButtonInvalid = errors.New("button data signature is invalid")
+	errButtonExpired = errors.New("button data is expired")
+	errButtonForeign = errors.New("button is bound to another user")
+)
+
+// ButtonSigning configures signing of button data, so users cannot craft
+// commands with arbitrary data. Data is bound to chat, button command and
+// optionally to user (see models.WithButtonBoundUser).
+type ButtonSigning struct {
+	Key          []byte        // bot secret key is used if empty
+	TTL          time.Duration // clicks after TTL are rejected, zero means no expiry
+	InvalidReply LocalizedString
+	ExpiredReply LocalizedString
+	ForeignReply LocalizedString
+}
+
+var (
+	defaultInvalidButtonReply = LocalizedString{
+		"en": "This button is not valid.",
+		"ru": "Эта кнопка недействительна.",
+	}
+	defaultExpiredButtonReply = LocalizedString{
+		"en": "This button has expired, please request a new message.",
+		"ru": "Срок действия кнопки истёк, запросите новое сообщение.",
+	}
+	defaultForeignButtonReply = LocalizedString{
+		"en": "This button is meant for another user.",
+		"ru": "Эта кнопка предназначена другому пользователю.",
+	}
+)
+
+// signedButtonData replaces data of outgoing buttons. Data is kept as JSON
+// string, so it survives re-encoding by the server byte to byte.
+type signedButtonData struct {
+	Envelope signedEnvelope `json:"botx_signed"`
+}
+
+type signedEnvelope struct {
+	Data      string    `json:"d"`
+	ChatId    uuid.UUID `json:"c"`
+	UserHUID  uuid.UUID `json:"u"`
+	ExpiresAt int64     `json:"e,omitempty"`
+	Signature string    `json:"s"`
+}
+
+func (bs *ButtonSigning) signature(command string, env *signedEnvelope) string {
+	mac := hmac.New(sha256.New, bs.Key)
+	mac.Write([]byte(strings.Join([]string{
+		strings.ToLower(command),
+		env.ChatId.String(),
+		env.UserHUID.String(),
+		strconv.FormatInt(env.ExpiresAt, 10),
+		env.Data,
+	}, "\n")))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (bs *ButtonSigning) sign(command string, chatId uuid.UUID, userHUID uuid.UUID, data []byte) signedButtonData {
+	env := signedEnvelope{Data: string(data), ChatId: chatId, UserHUID: userHUID}
+	if bs.TTL > 0 {
+		env.ExpiresAt = time.Now().Add(bs.TTL).Unix()
+	}
+	env.Signature = bs.signature(command, &env)
+	return signedButtonData{Envelope: env}
+}
+
+func (bs *ButtonSigning) verify(req *models.CommandRequest) (json.RawMessage, error) {
+	var signed signedButtonData
+	if json.Unmarshal(req.Command.Data, &signed) != nil || signed.Envelope.Signature == "" {
+		return nil, errButtonInvalid
+	}
+	env := &signed.Envelope
+	expected := bs.signature(CommandName(req), env)
+	if !hmac.Equal([]byte(expected), []byte(env.Signature)) || env.ChatId != req.From.GroupChatId {
+		return nil, errButtonInvalid
+	}
+	if env.ExpiresAt > 0 && time.Now().Unix() > env.ExpiresAt {
+		return nil, errButtonExpired
+	}
+	if env.UserHUID != uuid.Nil && env.UserHUID != req.From.UserHUID {
+		return nil, errButtonForeign
+	}
+	return json.RawMessage(env.Data), nil
+}
+
+// ButtonDataStore keeps button data on server side while messages carry only
+// a short token. Implementations must be safe for concurrent use.
+type ButtonDataStore interface {
+	// Put stores data for ttl and returns token referencing it.
+	Put(ctx context.Context, data []byte, ttl time.Duration) (string, error)
+	// Get returns data by token, ok is false if token is unknown or expired.
+	Get(ctx context.Context, token string) (data []byte, ok bool, err error)
+}
+
+type memoryButtonDataStore struct {
+	data *kvCache[string, []byte]
+}
+
+// NewMemoryButtonDataStore returns in-process ButtonDataStore keeping data for ttl
+// when Put is called with zero ttl.
+func NewMemoryButtonDataStore(ttl time.Duration) ButtonDataStore {
+	return &memoryButtonDataStore{data: newTTLCache[string, []byte](ttl)}
+}
+
+func (s *memoryButtonDataStore) Put(_ context.Context, data []byte, ttl time.Duration) (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	t := base64.RawURLEncoding.EncodeToString(token)
+	if ttl <= 0 {
+		s.data.Set(t, data)
+	} else {
+		s.data.SetFor(t, data, ttl)
+	}
+	return t, nil
+}
+
+func (s *memoryButtonDataStore) Get(_ context.Context, token string) ([]byte, bool, error) {
+	data, ok := s.data.Get(token)
+	return data, ok, nil
+}
+
+// storedButtonData replaces data of outgoing buttons when ButtonDataStore is set.
+type storedButtonData struct {
+	Token string `json:"botx_ref"`
+}
+
+// encodeButtons prepares data of message buttons for sending: one-time buttons
+// are marked, then data is put to the store and the result is signed. Buttons
+// are changed in place, so callers pass copies made by copyButtons.
+func (b *Bot) encodeButtons(ctx context.Context, chatId uuid.UUID, bubble, keyboard models.NDButtons) error {
+	for k, rows := range []models.NDButtons{bubble, keyboard} {
+		for r, row := range rows {
+			for c := range row {
+				ref := oneTimeRef{Keyboard: k == 1, Row: r, Column: c}
+				if err := b.encodeButtonData(ctx, chatId, &row[c], ref); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// encodeButtonData encodes data of command button. With signing enabled buttons
+// without data are signed too, so their chat and user binding is checked.
+func (b *Bot) encodeButtonData(ctx context.Context, chatId uuid.UUID, button *models.NDButton, ref oneTimeRef) error {
+	if button.Command == "" || (button.Data == nil && !button.OneTime && b.buttonSigning == nil) {
+		return nil
+	}
+	if _, signed := button.Data.(signedButtonData); signed {
+		return nil
+	}
+	_, stored := button.Data.(storedButtonData)
+	_, once := button.Data.(oneTimeButtonData)
+	if button.OneTime && !once && !stored {
+		var data []byte
+		if button.Data != nil {
+			var err error
+			if data, err = json.Marshal(button.Data); err != nil {
+				return err
+			}
+		}
+		button.Data = oneTimeButtonData{Once: &ref, Data: data}
+	}
+	if b.buttonStore != nil && !stored && button.Data != nil {
+		data, err := json.Marshal(button.Data)
+		if err != nil {
+			return err
+		}
+		token, err := b.buttonStore.Put(ctx, data, b.buttonStoreTTL)
+		if err != nil {
+			return fmt.Errorf("failed to store button data: %w", err)
+		}
+		button.Data = storedButtonData{Token: token}
+	}
+	if b.buttonSigning != nil {
+		data := []byte("{}")
+		if button.Data != nil {
+			var err error
+			if data, err = json.Marshal(button.Data); err != nil {
+				return err
+			}
+		}
+		button.Data = b.buttonSigning.sign(firstWord(button.Command), chatId, button.BoundUserHUID, data)
+	}
+	return nil
+}
+
+// decodeCommandData verifies and restores data of clicked button in req.
+// Plain messages carry "{}" data and pass as is. With signing enabled, unsigned
+// button data is dropped, so handlers never see data they cannot trust.
+// Position of clicked one-time button is returned, it is claimed by caller.
+func (b *Bot) decodeCommandData(ctx context.Context, req *models.CommandRequest) (*oneTimeRef, error) {
+	if isEmptyCommandData(req.Command.Data) {
+		return nil, nil
+	}
+	if b.buttonSigning != nil {
+		if !isSignedButtonData(req.Command.Data) {
+			b.logger.Warn("unsigned button data dropped", "sync_id", req.SyncId, "user_huid", req.From.UserHUID)
+			req.Command.Data = nil
+			return nil, nil
+		}
+		data, err := b.buttonSigning.verify(req)
+		if err != nil {
+			return nil, err
+		}
+		req.Command.Data = data
+	}
+	if b.buttonStore != nil {
+		var ref storedButtonData
+		if json.Unmarshal(req.Command.Data, &ref) == nil && ref.Token != "" {
+			data, ok, err := b.buttonStore.Get(ctx, ref.Token)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load button data: %w", err)
+			}
+			if !ok {
+				return nil, errButtonExpired
+			}
+			req.Command.Data = data
+		}
+	}
+	return unwrapOneTimeButton(req)
+}
+
+func isEmptyCommandData(data json.RawMessage) bool {
+	var fields map[string]json.RawMessage
+	return len(data) == 0 || string(data) == "null" || (json.Unmarshal(data, &fields) == nil && len(fields) == 0)
+}
+
+func isSignedButtonData(data json.RawMessage) bool {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(data, &fields) != nil {
+		return false
+	}
+	_, ok := fields["botx_signed"]
+	return ok
+}
+
+// copyButtons copies rows, so encoding them leaves caller's buttons intact.
+func copyButtons(buttons models.NDButtons) models.NDButtons {
+	if buttons == nil {
+		return nil
+	}
+	c := make(models.NDButtons, len(buttons))
+	for i, row := range buttons {
+		c[i] = append(models.NDButtonRow(nil), row...)
+	}
+	return c
+}
+
+// rejectButton tells user why button click was rejected.
+func (b *Bot) rejectButton(ctx context.Context, req *models.CommandRequest, err error) {
+	signing := b.buttonSigning
+	if signing == nil {
+		signing = &ButtonSigning{
+			InvalidReply: defaultInvalidButtonReply,
+			ExpiredReply: defaultExpiredButtonReply,
+			ForeignReply: defaultForeignButtonReply,
+		}
+	}
+	var reply LocalizedString
+	switch err {
+	case errButtonInvalid:
+		reply = signing.InvalidReply
+	case errButtonExpired:
+		reply = signing.ExpiredReply
+	case errButtonForeign:
+		reply = signing.ForeignReply
+	case errButtonUsed:
+		reply = defaultUsedButtonReply
+	default:
+		go b.replyError(ctx, req, err)
+		return
+	}
+	b.logger.Warn("button click rejected", "sync_id", req.SyncId, "user_huid", req.From.UserHUID, "error", err)
+	go b.reply(req, reply.For(req.From.Locale))
+}
+
+func firstWord(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexAny(s, " \t\n"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -0,0 +1,166 @@
+package botx
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/go-botx/botx/models"
+	"github.com/google/uuid"
+)
+
+// clickOn returns request of a click on button of message sent to chatId.
+func clickOn(t *testing.T, b *Bot, chatId uuid.UUID, button models.NDButton) *models.CommandRequest {
+	t.Helper()
+	rows := models.NDButtons{{button}}
+	if err := b.encodeButtons(context.Background(), chatId, rows, nil); err != nil {
+		t.Fatal(err)
+	}
+	req := newTestCommand(button.Command)
+	req.From.GroupChatId = chatId
+	req.Command.Data, _ = json.Marshal(rows[0][0].Data)
+	return req
+}
+
+func TestSignedButtonData(t *testing.T) {
+	b := newTestBot(t, WithButtonSigning(ButtonSigning{TTL: time.Minute}))
+	ctx := context.Background()
+	chatId, userHUID := uuid.New(), uuid.New()
+	button := models.NewCommandButton("Pick", "/pick", map[string]int{"n": 1}, models.WithButtonBoundUser(userHUID))
+
+	t.Run("plain text", func(t *testing.T) {
+		for _, data := range []string{``, `null`, `{}`, ` { } `} {
+			req := newTestCommand("hello")
+			req.Command.Data = json.RawMessage(data)
+			if _, err := b.decodeCommandData(ctx, req); err != nil {
+				t.Errorf("data %q: decodeCommandData() = %v", data, err)
+			}
+		}
+	})
+	t.Run("valid", func(t *testing.T) {
+		req := clickOn(t, b, chatId, button)
+		req.From.UserHUID = userHUID
+		if _, err := b.decodeCommandData(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+		if string(req.Command.Data) != `{"n":1}` {
+			t.Errorf("data = %s, want {\"n\":1}", req.Command.Data)
+		}
+	})
+	t.Run("unsigned", func(t *testing.T) {
+		req := newTestCommand("/pick")
+		req.Command.Data = json.RawMessage(`{"n":100}`)
+		if _, err := b.decodeCommandData(ctx, req); err != nil || req.Command.Data != nil {
+			t.Errorf("decodeCommandData() = %v with data %s, want unsigned data dropped", err, req.Command.Data)
+		}
+	})
+	t.Run("forged", func(t *testing.T) {
+		req := clickOn(t, b, chatId, button)
+		req.From.UserHUID = userHUID
+		var signed signedButtonData
+		json.Unmarshal(req.Command.Data, &signed)
+		signed.Envelope.Data = `{"n":100}`
+		req.Command.Data, _ = json.Marshal(signed)
+		if _, err := b.decodeCommandData(ctx, req); err != errButtonInvalid {
+			t.Errorf("decodeCommandData() = %v, want %v", err, errButtonInvalid)
+		}
+	})
+	t.Run("other command", func(t *testing.T) {
+		req := clickOn(t, b, chatId, button)
+		req.From.UserHUID = userHUID
+		req.Command.Body = "/delete"
+		if _, err := b.decodeCommandData(ctx, req); err != errButtonInvalid {
+			t.Errorf("decodeCommandData() = %v, want %v", err, errButtonInvalid)
+		}
+	})
+	t.Run("other chat", func(t *testing.T) {
+		req := clickOn(t, b, chatId, button)
+		req.From.UserHUID = userHUID
+		req.From.GroupChatId = uuid.New()
+		if _, err := b.decodeCommandData(ctx, req); err != errButtonInvalid {
+			t.Errorf("decodeCommandData() = %v, want %v", err, errButtonInvalid)
+		}
+	})
+	t.Run("foreign", func(t *testing.T) {
+		req := clickOn(t, b, chatId, button)
+		if _, err := b.decodeCommandData(ctx, req); err != errButtonForeign {
+			t.Errorf("decodeCommandData() = %v, want %v", err, errButtonForeign)
+		}
+	})
+	t.Run("bound without data", func(t *testing.T) {
+		approve := models.NewCommandButton[any]("Approve", "/approve", nil, models.WithButtonBoundUser(userHUID))
+		req := clickOn(t, b, chatId, approve)
+		if _, err := b.decodeCommandData(ctx, req); err != errButtonForeign {
+			t.Errorf("decodeCommandData() = %v, want %v", err, errButtonForeign)
+		}
+		req = clickOn(t, b, chatId, approve)
+		req.From.UserHUID = userHUID
+		if _, err := b.decodeCommandData(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+		if !isEmptyCommandData(req.Command.Data) {
+			t.Errorf("data = %s, want empty", req.Command.Data)
+		}
+	})
+	t.Run("expired", func(t *testing.T) {
+		env := signedEnvelope{Data: `{"n":1}`, ChatId: chatId, ExpiresAt: time.Now().Add(-time.Second).Unix()}
+		env.Signature = b.buttonSigning.signature("/pick", &env)
+		req := newTestCommand("/pick")
+		req.From.GroupChatId = chatId
+		req.Command.Data, _ = json.Marshal(signedButtonData{Envelope: env})
+		if _, err := b.decodeCommandData(ctx, req); err != errButtonExpired {
+			t.Errorf("decodeCommandData() = %v, want %v", err, errButtonExpired)
+		}
+	})
+}
+
+func TestStoredButtonData(t *testing.T) {
+	b := newTestBot(t, WithButtonSigning(ButtonSigning{}), WithButtonDataStore(time.Minute))
+	ctx := context.Background()
+	req := clickOn(t, b, uuid.New(), models.NewCommandButton("Pick", "/pick", []int{1, 2, 3}))
+	if _, err := b.decodeCommandData(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if string(req.Command.Data) != `[1,2,3]` {
+		t.Errorf("data = %s, want [1,2,3]", req.Command.Data)
+	}
+
+	req = newTestCommand("/pick")
+	req.Command.Data = json.RawMessage(`{"botx_ref":"unknown"}`)
+	if _, err := newTestBot(t, WithButtonDataStore(time.Minute)).decodeCommandData(ctx, req); err != errButtonExpired {
+		t.Errorf("unknown token: decodeCommandData() = %v, want %v", err, errButtonExpired)
+	}
+}
+
+func TestMemoryButtonDataStoreTTL(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryButtonDataStore(time.Hour)
+	token, err := store.Put(ctx, []byte(`1`), time.Nanosecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	if _, ok, _ := store.Get(ctx, token); ok {
+		t.Error("data is kept after its ttl")
+	}
+	if token, err = store.Put(ctx, []byte(`1`), 0); err != nil {
+		t.Fatal(err)
+	}
+	if data, ok, _ := store.Get(ctx, token); !ok || string(data) != `1` {
+		t.Errorf("Get() = %s, %t; want data kept for store ttl", data, ok)
+	}
+}
+
+func TestSendMessageKeepsButtons(t *testing.T) {
+	b := newTestBot(t, WithButtonSigning(ButtonSigning{}))
+	message, err := models.NewNDRequest(uuid.New(), "hi", models.WithNDBubbleRow(models.NewCommandButton("Pick", "/pick", 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// API is unreachable, buttons are encoded before sending anyway
+	b.SendMessageAsync(message)
+	if data := message.Notification.Bubble[0][0].Data; data != 1 {
+		t.Errorf("caller's button data changed to %v", data)
+	}
+}
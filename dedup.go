@@ -0,0 +1,74 @@
+package botx
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-botx/botx/models"
+	"github.com/google/uuid"
+)
+
+// DedupStore remembers sync ids of accepted commands so retried callbacks are
+// not handled twice. Implementations must be safe for concurrent use.
+type DedupStore interface {
+	// MarkSeen records syncId for ttl, returns false if it is already recorded.
+	MarkSeen(ctx context.Context, syncId uuid.UUID, ttl time.Duration) (bool, error)
+	// Forget removes syncId, so the command is accepted again when retried.
+	Forget(ctx context.Context, syncId uuid.UUID) error
+}
+
+type memoryDedupStore struct {
+	seen *kvCache[uuid.UUID, struct{}]
+}
+
+// NewMemoryDedupStore returns in-process DedupStore keeping ids for ttl when
+// MarkSeen is called with zero ttl.
+func NewMemoryDedupStore(ttl time.Duration) DedupStore {
+	return &memoryDedupStore{seen: newTTLCache[uuid.UUID, struct{}](ttl)}
+}
+
+func (s *memoryDedupStore) MarkSeen(_ context.Context, syncId uuid.UUID, ttl time.Duration) (bool, error) {
+	if ttl <= 0 {
+		return s.seen.SetIfAbsent(syncId, struct{}{}), nil
+	}
+	return s.seen.SetIfAbsentFor(syncId, struct{}{}, ttl), nil
+}
+
+func (s *memoryDedupStore) Forget(_ context.Context, syncId uuid.UUID) error {
+	s.seen.Delete(syncId)
+	return nil
+}
+
+// DuplicateCommandHook is called for every command dropped as a duplicate.
+type DuplicateCommandHook func(b *Bot, req *models.CommandRequest)
+
+// isDuplicateCommand marks req as seen, returns true if it has been seen before.
+// Store failures are logged and the command is treated as new.
+func (b *Bot) isDuplicateCommand(ctx context.Context, req *models.CommandRequest) bool {
+	if b.dedupStore == nil {
+		return false
+	}
+	firstSeen, err := b.dedupStore.MarkSeen(ctx, req.SyncId, b.dedupTTL)
+	if err != nil {
+		b.logger.Error("failed to check command for duplicate", "sync_id", req.SyncId, "error", err)
+		return false
+	}
+	if firstSeen {
+		return false
+	}
+	b.logger.Debug("duplicate command dropped", "sync_id", req.SyncId)
+	if b.duplicateCommandHook != nil {
+		b.duplicateCommandHook(b, req)
+	}
+	return true
+}
+
+// forgetCommand lets command refused before handling to be accepted on retry.
+func (b *Bot) forgetCommand(ctx context.Context, req *models.CommandRequest) {
+	if b.dedupStore == nil {
+		return
+	}
+	if err := b.dedupStore.Forget(ctx, req.SyncId); err != nil {
+		b.logger.Error("failed to forget refused command", "sync_id", req.SyncId, "error", err)
+	}
+}
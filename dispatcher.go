@@ -0,0 +1,155 @@
+package botx
+
+import (
+	"errors"
+	"runtime/debug"
+	"sync"
+
+	"github.com/go-botx/botx/models"
+)
+
+var (
+	errBotShuttingDown  = errors.New("bot is shutting down")
+	errCommandQueueFull = errors.New("command queue is full")
+	// reported instead of the WAL error, which may reveal file system details
+	errCommandNotPersisted = errors.New("bot is temporarily unavailable, please try again later")
+)
+
+// submitCommand schedules command handling, either on a fresh goroutine or on
+// the worker pool configured with WithCommandWorkers. Commands sharing a key of
+// WithOrderedCommands are queued and handled one by one.
+func (b *Bot) submitCommand(req *models.CommandRequest) error {
+	b.shutdownMtx.RLock()
+	defer b.shutdownMtx.RUnlock()
+	if b.shuttingDown {
+		return errBotShuttingDown
+	}
+	b.handlersWG.Add(1)
+	var err error
+	if b.commandOrder == nil {
+		err = b.schedule(func() {
+			defer b.handlersWG.Done()
+			b.executeCommand(req)
+		})
+	} else {
+		err = b.commandOrder.push(req, func(key string) error {
+			return b.schedule(func() { b.drainOrderedCommands(key, req) })
+		})
+	}
+	if err != nil {
+		b.handlersWG.Done()
+	}
+	return err
+}
+
+func (b *Bot) schedule(task func()) error {
+	if b.commandQueue == nil {
+		go task()
+		return nil
+	}
+	select {
+	case b.commandQueue <- task:
+		return nil
+	default:
+		return errCommandQueueFull
+	}
+}
+
+func (b *Bot) startCommandWorkers(workers int) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for task := range b.commandQueue {
+				task()
+			}
+		}()
+	}
+}
+
+func (b *Bot) drainOrderedCommands(key string, first *models.CommandRequest) {
+	for req := first; req != nil; req = b.commandOrder.next(key) {
+		b.executeCommand(req)
+		b.handlersWG.Done()
+	}
+}
+
+func (b *Bot) executeCommand(req *models.CommandRequest) {
+	if b.ctx.Err() != nil || !b.commandStarted(req) {
+		// Shutdown timed out before handler started, command is left pending
+		// in WAL to be handled on next start
+		b.logger.Debug("command is not handled on shutdown", "sync_id", req.SyncId)
+		return
+	}
+	defer b.commandDone(req)
+	defer func() {
+		if r := recover(); r != nil {
+			b.logger.Error("command handler panicked",
+				"panic", r, "sync_id", req.SyncId, "stack", string(debug.Stack()))
+		}
+	}()
+	ctx, cancel := b.commandContext()
+	defer cancel()
+	defer b.watchCommand(ctx, req)()
+	b.router.resolve(req, b.commandCallbackHandler)(ctx, b, req)
+}
+
+// CommandOrderKey groups commands that must be handled sequentially.
+type CommandOrderKey func(req *models.CommandRequest) string
+
+var (
+	OrderByChat CommandOrderKey = func(req *models.CommandRequest) string {
+		return req.From.GroupChatId.String()
+	}
+	OrderByUser CommandOrderKey = func(req *models.CommandRequest) string {
+		return req.From.UserHUID.String()
+	}
+)
+
+type orderedCommands struct {
+	key       CommandOrderKey
+	queueSize int
+	mtx       sync.Mutex
+	pending   map[string][]*models.CommandRequest // waiting commands, key is present while its commands are being drained
+}
+
+func newOrderedCommands(key CommandOrderKey, queueSize int) *orderedCommands {
+	return &orderedCommands{
+		key:       key,
+		queueSize: queueSize,
+		pending:   make(map[string][]*models.CommandRequest),
+	}
+}
+
+// push queues req after commands with the same key, calling start to launch
+// draining with req when there are none.
+func (oc *orderedCommands) push(req *models.CommandRequest, start func(key string) error) error {
+	key := oc.key(req)
+	oc.mtx.Lock()
+	defer oc.mtx.Unlock()
+	if queue, draining := oc.pending[key]; draining {
+		if len(queue) >= oc.queueSize {
+			return errCommandQueueFull
+		}
+		oc.pending[key] = append(queue, req)
+		return nil
+	}
+	if err := start(key); err != nil {
+		return err
+	}
+	oc.pending[key] = []*models.CommandRequest{}
+	return nil
+}
+
+// next pops next command for key, returns nil and forgets key when its queue is empty.
+func (oc *orderedCommands) next(key string) *models.CommandRequest {
+	oc.mtx.Lock()
+	defer oc.mtx.Unlock()
+	queue := oc.pending[key]
+	if len(queue) == 0 {
+		delete(oc.pending, key)
+		return nil
+	}
+	req := queue[0]
+	queue[0] = nil
+	oc.pending[key] = queue[1:]
+	return req
+}
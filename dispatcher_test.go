@@ -0,0 +1,123 @@
+package botx
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-botx/botx/models"
+	"github.com/google/uuid"
+)
+
+func TestShutdownTwice(t *testing.T) {
+	started := make(chan struct{})
+	b := newTestBot(t, WithCommandWorkers(1, 1), WithCommandContextHandler(func(ctx context.Context, b *Bot, req *models.CommandRequest) {
+		close(started)
+		<-ctx.Done()
+	}))
+	if err := b.submitCommand(newTestCommand("/wait")); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("first Shutdown() = %v, want deadline exceeded", err)
+	}
+	if err := b.Shutdown(context.Background()); err != nil {
+		t.Fatalf("second Shutdown() = %v", err)
+	}
+	if err := b.submitCommand(newTestCommand("/late")); err != errBotShuttingDown {
+		t.Fatalf("submit after Shutdown() = %v, want %v", err, errBotShuttingDown)
+	}
+}
+
+func TestOrderedCommandsKeepOrderPerChat(t *testing.T) {
+	var mtx sync.Mutex
+	handled := map[uuid.UUID][]int{}
+	var running atomic.Int32
+	b := newTestBot(t, WithCommandWorkers(4, 100), WithOrderedCommands(OrderByChat, 100),
+		WithCommandContextHandler(func(ctx context.Context, b *Bot, req *models.CommandRequest) {
+			if running.Add(1) > 2 {
+				t.Error("commands of one chat are handled concurrently")
+			}
+			defer running.Add(-1)
+			time.Sleep(time.Millisecond)
+			n, _ := strconv.Atoi(req.Command.Body)
+			mtx.Lock()
+			handled[req.From.GroupChatId] = append(handled[req.From.GroupChatId], n)
+			mtx.Unlock()
+		}))
+	chats := []uuid.UUID{uuid.New(), uuid.New()}
+	for i := 0; i < 20; i++ {
+		for _, chatId := range chats {
+			req := newTestCommand(strconv.Itoa(i))
+			req.From.GroupChatId = chatId
+			if err := b.submitCommand(req); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := b.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, chatId := range chats {
+		got := handled[chatId]
+		if len(got) != 20 {
+			t.Fatalf("chat handled %d commands, want 20", len(got))
+		}
+		for i, n := range got {
+			if n != i {
+				t.Fatalf("chat commands handled in order %v", got)
+			}
+		}
+	}
+}
+
+func TestOrderedCommandsRunChatsInParallel(t *testing.T) {
+	otherDone := make(chan struct{})
+	first := newTestCommand("first")
+	b := newTestBot(t, WithOrderedCommands(OrderByChat, 10),
+		WithCommandContextHandler(func(ctx context.Context, b *Bot, req *models.CommandRequest) {
+			if req == first {
+				select {
+				case <-otherDone:
+				case <-time.After(time.Second):
+					t.Error("command of other chat waits for blocked chat")
+				}
+				return
+			}
+			close(otherDone)
+		}))
+	b.submitCommand(first)
+	b.submitCommand(newTestCommand("other"))
+	b.Shutdown(context.Background())
+}
+
+func TestOrderedCommandsQueueFull(t *testing.T) {
+	release := make(chan struct{})
+	b := newTestBot(t, WithOrderedCommands(OrderByUser, 1),
+		WithCommandContextHandler(func(ctx context.Context, b *Bot, req *models.CommandRequest) {
+			<-release
+		}))
+	user := uuid.New()
+	var errs []error
+	for i := 0; i < 3; i++ {
+		req := newTestCommand("/a")
+		req.From.UserHUID = user
+		errs = append(errs, b.submitCommand(req))
+	}
+	other := newTestCommand("/a")
+	errs = append(errs, b.submitCommand(other))
+	close(release)
+	b.Shutdown(context.Background())
+	// first is running, second waits, third is refused, other user is not affected
+	if errs[0] != nil || errs[1] != nil || errs[2] != errCommandQueueFull || errs[3] != nil {
+		t.Fatalf("submitCommand() errors = %v", errs)
+	}
+}
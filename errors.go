@@ -0,0 +1,105 @@
+package botx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-botx/botx/models"
+)
+
+// CommandCallbackErrorHandler is a command handler reporting failure with
+// error. Use HandleErrors to turn it into CommandCallbackContextHandler.
+type CommandCallbackErrorHandler func(ctx context.Context, b *Bot, req *models.CommandRequest) error
+
+// LocalizedError is an error whose text may be shown to user as is.
+// UserError and ArgsError implement it.
+type LocalizedError interface {
+	error
+	Message(locale string) string
+}
+
+// UserError is an error caused by user input or state, its message is shown to
+// the user verbatim.
+type UserError struct {
+	message LocalizedString
+	Err     error
+}
+
+func NewUserError(message string) *UserError {
+	return &UserError{message: LocalizedString{defaultLocale: message}}
+}
+
+func UserErrorf(format string, args ...any) *UserError {
+	err := fmt.Errorf(format, args...)
+	return &UserError{message: LocalizedString{defaultLocale: err.Error()}, Err: errors.Unwrap(err)}
+}
+
+// NewLocalizedUserError creates UserError with message translations.
+func NewLocalizedUserError(message LocalizedString) *UserError {
+	return &UserError{message: message}
+}
+
+func (e *UserError) Error() string {
+	return e.message.For(defaultLocale)
+}
+
+func (e *UserError) Message(locale string) string {
+	return e.message.For(locale)
+}
+
+func (e *UserError) Unwrap() error {
+	return e.Err
+}
+
+// Placeholders of error reply templates.
+const (
+	ErrorReplyMessage = "{message}"
+	ErrorReplySyncId  = "{sync_id}"
+)
+
+var (
+	defaultUserErrorReply = LocalizedString{
+		"en": ErrorReplyMessage,
+		"ru": ErrorReplyMessage,
+	}
+	defaultInternalErrorReply = LocalizedString{
+		"en": "Something went wrong, please try again later.\nRequest id: " + ErrorReplySyncId,
+		"ru": "Что-то пошло не так, попробуйте позже.\nИдентификатор запроса: " + ErrorReplySyncId,
+	}
+)
+
+// HandleErrors adapts handler returning error. User facing errors (LocalizedError)
+// are replied to the originating chat verbatim, other errors are logged and
+// replaced with a generic message carrying request sync id for correlation.
+func HandleErrors(handler CommandCallbackErrorHandler) CommandCallbackContextHandler {
+	return func(ctx context.Context, b *Bot, req *models.CommandRequest) {
+		if err := handler(ctx, b, req); err != nil {
+			b.replyError(ctx, req, err)
+		}
+	}
+}
+
+func (b *Bot) replyError(ctx context.Context, req *models.CommandRequest, err error) {
+	locale := req.From.Locale
+	var template, message string
+	var localized LocalizedError
+	switch {
+	case errors.As(err, &localized):
+		template = b.userErrorReply.For(locale)
+		message = localized.Message(locale)
+	case ctx.Err() != nil && errors.Is(err, ctx.Err()):
+		// cancelled on shutdown or timed out, the latter is reported by watchdog
+		b.logger.Warn("command handler interrupted", "sync_id", req.SyncId, "error", err)
+		return
+	default:
+		b.logger.Error("command handler failed",
+			"sync_id", req.SyncId, "body", req.Command.Body, "error", err)
+		template = b.internalErrorReply.For(locale)
+	}
+	text := strings.NewReplacer(ErrorReplyMessage, message, ErrorReplySyncId, req.SyncId.String()).Replace(template)
+	if _, errR := b.Reply(req, text); errR != nil {
+		b.logger.Error("failed to send error reply", "sync_id", req.SyncId, "error", errR)
+	}
+}
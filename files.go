@@ -0,0 +1,81 @@
+package botx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/go-botx/botx/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+var ErrAsyncFileTooLarge = errors.New("async file exceeds maximum allowed size")
+
+// OpenAsyncFile starts download of file sent with req and returns its content
+// stream. Caller must close returned reader.
+func (b *Bot) OpenAsyncFile(req *models.CommandRequest, file models.AsyncFile) (io.ReadCloser, error) {
+	return b.openAsyncFile(req, file, false)
+}
+
+// OpenAsyncFilePreview is like OpenAsyncFile but downloads file preview.
+func (b *Bot) OpenAsyncFilePreview(req *models.CommandRequest, file models.AsyncFile) (io.ReadCloser, error) {
+	if !file.HasPreview() {
+		return nil, errors.New("async file has no preview")
+	}
+	return b.openAsyncFile(req, file, true)
+}
+
+func (b *Bot) openAsyncFile(req *models.CommandRequest, file models.AsyncFile, preview bool) (io.ReadCloser, error) {
+	if !preview && b.asyncFileMaxSize > 0 && file.FileSize > b.asyncFileMaxSize {
+		return nil, ErrAsyncFileTooLarge
+	}
+	dr := models.NewDownloadFileRequest(req.From.GroupChatId, file.FileId, preview)
+	httpReq, err := http.NewRequest(dr.Method(), "https://"+b.account.CTSHost+dr.RelativeReference(), nil)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set(fiber.HeaderAuthorization, "Bearer "+b.getToken())
+	resp, err := b.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		reason, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to download file %s: status %d: %s", file.FileId, resp.StatusCode, reason)
+	}
+	if b.asyncFileMaxSize <= 0 {
+		return resp.Body, nil
+	}
+	if resp.ContentLength > b.asyncFileMaxSize {
+		resp.Body.Close()
+		return nil, ErrAsyncFileTooLarge
+	}
+	return &limitedReadCloser{rc: resp.Body, left: b.asyncFileMaxSize}, nil
+}
+
+// limitedReadCloser fails with ErrAsyncFileTooLarge when more than left bytes are read.
+type limitedReadCloser struct {
+	rc   io.ReadCloser
+	left int64
+}
+
+func (l *limitedReadCloser) Read(p []byte) (n int, err error) {
+	if l.left < 0 {
+		return 0, ErrAsyncFileTooLarge
+	}
+	if int64(len(p)) > l.left+1 {
+		p = p[:l.left+1]
+	}
+	n, err = l.rc.Read(p)
+	l.left -= int64(n)
+	if l.left < 0 {
+		return n + int(l.left), ErrAsyncFileTooLarge
+	}
+	return n, err
+}
+
+func (l *limitedReadCloser) Close() error {
+	return l.rc.Close()
+}
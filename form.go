@@ -0,0 +1,428 @@
+package botx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-botx/botx/models"
+)
+
+type FormFieldKind string
+
+const (
+	FormFieldText   = FormFieldKind("text")
+	FormFieldNumber = FormFieldKind("number")
+	FormFieldDate   = FormFieldKind("date")
+	FormFieldChoice = FormFieldKind("choice")
+	FormFieldUser   = FormFieldKind("user")
+	FormFieldFile   = FormFieldKind("file")
+)
+
+// FormField describes a question of a form. Answer is converted to the type of
+// struct field named Field like command arguments are (see BindArgs); file
+// fields must be models.AsyncFile or models.Attachment.
+type FormField struct {
+	Field    string
+	Kind     FormFieldKind
+	Prompt   LocalizedString
+	Choices  []FormChoice
+	Optional bool
+	Rules    []FormRule
+}
+
+// FormChoice is an answer option shown as bubble button.
+type FormChoice struct {
+	Label LocalizedString
+	Value string
+}
+
+// FormRule validates converted answer, error should be a LocalizedError to be
+// shown to user.
+type FormRule func(value any) error
+
+func FormText(field string, prompt LocalizedString, rules ...FormRule) FormField {
+	return FormField{Field: field, Kind: FormFieldText, Prompt: prompt, Rules: rules}
+}
+
+func FormNumber(field string, prompt LocalizedString, rules ...FormRule) FormField {
+	return FormField{Field: field, Kind: FormFieldNumber, Prompt: prompt, Rules: rules}
+}
+
+func FormDate(field string, prompt LocalizedString, rules ...FormRule) FormField {
+	return FormField{Field: field, Kind: FormFieldDate, Prompt: prompt, Rules: rules}
+}
+
+func FormChoices(field string, prompt LocalizedString, choices ...FormChoice) FormField {
+	return FormField{Field: field, Kind: FormFieldChoice, Prompt: prompt, Choices: choices}
+}
+
+func FormUser(field string, prompt LocalizedString, rules ...FormRule) FormField {
+	return FormField{Field: field, Kind: FormFieldUser, Prompt: prompt, Rules: rules}
+}
+
+func FormFile(field string, prompt LocalizedString, rules ...FormRule) FormField {
+	return FormField{Field: field, Kind: FormFieldFile, Prompt: prompt, Rules: rules}
+}
+
+// MinLength requires text of at least n characters.
+func MinLength(n int) FormRule {
+	return func(value any) error {
+		if s, ok := value.(string); ok && utf8.RuneCountInString(s) < n {
+			return NewLocalizedUserError(LocalizedString{
+				"en": fmt.Sprintf("Answer must be at least %d characters long.", n),
+				"ru": fmt.Sprintf("Ответ должен содержать не менее %d символов.", n),
+			})
+		}
+		return nil
+	}
+}
+
+// MaxLength requires text of at most n characters.
+func MaxLength(n int) FormRule {
+	return func(value any) error {
+		if s, ok := value.(string); ok && utf8.RuneCountInString(s) > n {
+			return NewLocalizedUserError(LocalizedString{
+				"en": fmt.Sprintf("Answer must be at most %d characters long.", n),
+				"ru": fmt.Sprintf("Ответ должен содержать не более %d символов.", n),
+			})
+		}
+		return nil
+	}
+}
+
+// Between requires number within [min, max].
+func Between(min float64, max float64) FormRule {
+	return func(value any) error {
+		v := reflect.ValueOf(value)
+		var f float64
+		switch {
+		case v.CanInt():
+			f = float64(v.Int())
+		case v.CanUint():
+			f = float64(v.Uint())
+		case v.CanFloat():
+			f = v.Float()
+		default:
+			return nil
+		}
+		if f < min || f > max {
+			return NewLocalizedUserError(LocalizedString{
+				"en": fmt.Sprintf("Number must be between %g and %g.", min, max),
+				"ru": fmt.Sprintf("Число должно быть от %g до %g.", min, max),
+			})
+		}
+		return nil
+	}
+}
+
+// Matches requires text matching re, message explains expected format.
+func Matches(re *regexp.Regexp, message LocalizedString) FormRule {
+	return func(value any) error {
+		if s, ok := value.(string); ok && !re.MatchString(s) {
+			return NewLocalizedUserError(message)
+		}
+		return nil
+	}
+}
+
+// FormCompleteHandler receives filled form.
+type FormCompleteHandler[T any] func(ctx context.Context, b *Bot, req *models.CommandRequest, result *T) error
+
+type FormOption func(f *formTexts)
+
+type formTexts struct {
+	back, cancel, skip, cancelled LocalizedString
+}
+
+func WithFormButtons(back LocalizedString, cancel LocalizedString, skip LocalizedString) FormOption {
+	return func(f *formTexts) {
+		f.back, f.cancel, f.skip = back, cancel, skip
+	}
+}
+
+func WithFormCancelledMessage(message LocalizedString) FormOption {
+	return func(f *formTexts) {
+		f.cancelled = message
+	}
+}
+
+const (
+	formActionBack   = "back"
+	formActionCancel = "cancel"
+	formActionSkip   = "skip"
+	formActionChoose = "choose"
+)
+
+type formButtonData struct {
+	Form   string `json:"form"`
+	Action string `json:"action"`
+	Value  string `json:"value,omitempty"`
+}
+
+// FormSession is a state of form dialog kept in FSMStorage.
+type FormSession[T any] struct {
+	Step   int `json:"step"`
+	Result T   `json:"result"`
+}
+
+const formState = "form"
+
+// Form runs a dialog asking fields one by one and calls completion handler
+// with filled struct T. Every prompt has "back" and "cancel" buttons, optional
+// fields also have "skip".
+type Form[T any] struct {
+	command    string
+	fields     []FormField
+	fieldIdx   []int
+	onComplete FormCompleteHandler[T]
+	fsm        *FSM[FormSession[T]]
+	texts      formTexts
+}
+
+// NewForm creates form started by command. Sessions are kept in storage if
+// given, in memory otherwise.
+func NewForm[T any](command string, fields []FormField, onComplete FormCompleteHandler[T], storage FSMStorage[FormSession[T]], options ...FormOption) (*Form[T], error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("form result must be a struct, got %s", t)
+	}
+	if len(fields) == 0 || onComplete == nil {
+		return nil, errors.New("form must have fields and completion handler")
+	}
+	f := &Form[T]{
+		command:    command,
+		fields:     fields,
+		onComplete: onComplete,
+		fsm:        NewFSM(storage),
+		texts: formTexts{
+			back:      LocalizedString{"en": "← Back", "ru": "← Назад"},
+			cancel:    LocalizedString{"en": "Cancel", "ru": "Отмена"},
+			skip:      LocalizedString{"en": "Skip", "ru": "Пропустить"},
+			cancelled: LocalizedString{"en": "Cancelled.", "ru": "Отменено."},
+		},
+	}
+	for _, field := range fields {
+		sf, ok := t.FieldByName(field.Field)
+		if !ok || !sf.IsExported() || len(sf.Index) != 1 {
+			return nil, fmt.Errorf("form result %s has no exported field %s", t, field.Field)
+		}
+		f.fieldIdx = append(f.fieldIdx, sf.Index[0])
+	}
+	for _, opt := range options {
+		opt(&f.texts)
+	}
+	f.fsm.On(formState, f.handle)
+	return f, nil
+}
+
+// Register routes form command to Start and user answers to the form.
+// While the dialog is active, other registered commands are handled as usual,
+// the form command restarts it and typed label of cancel button cancels it.
+func (f *Form[T]) Register(b *Bot) {
+	b.Handle(f.command, f.Start)
+	b.Use(f.middleware())
+}
+
+func (f *Form[T]) middleware() CommandMiddleware {
+	answers := f.fsm.Middleware()
+	return func(next CommandCallbackContextHandler) CommandCallbackContextHandler {
+		withAnswers := answers(next)
+		return func(ctx context.Context, b *Bot, req *models.CommandRequest) {
+			if _, click := f.buttonData(req); !click && b.router.hasRoute(CommandName(req)) {
+				next(ctx, b, req)
+				return
+			}
+			withAnswers(ctx, b, req)
+		}
+	}
+}
+
+// buttonData returns action of the form button clicked, click is true for
+// buttons of any form.
+func (f *Form[T]) buttonData(req *models.CommandRequest) (action formButtonData, click bool) {
+	if len(req.Command.Data) == 0 || json.Unmarshal(req.Command.Data, &action) != nil || action.Form == "" {
+		return formButtonData{}, false
+	}
+	return action, true
+}
+
+// Start begins the dialog with sender of req.
+func (f *Form[T]) Start(ctx context.Context, b *Bot, req *models.CommandRequest) {
+	if _, click := f.buttonData(req); click {
+		b.logger.Debug("form button clicked without active form", "sync_id", req.SyncId, "form", f.command)
+		return
+	}
+	var session FormSession[T]
+	if err := f.fsm.Start(ctx, FSMKeyOf(req), formState, session); err != nil {
+		b.replyError(ctx, req, err)
+		return
+	}
+	f.prompt(b, req, 0)
+}
+
+func (f *Form[T]) handle(ctx context.Context, b *Bot, req *models.CommandRequest, session *FormSession[T]) (string, error) {
+	action, click := f.buttonData(req)
+	if click && action.Form != f.command {
+		return formState, NewLocalizedUserError(LocalizedString{
+			"en": "This button does not belong to the current form.",
+			"ru": "Эта кнопка не относится к текущей форме.",
+		})
+	}
+	if !click && strings.EqualFold(strings.TrimSpace(req.Command.Body), f.texts.cancel.For(req.From.Locale)) {
+		action.Action = formActionCancel
+	}
+	field := f.fields[session.Step]
+	switch action.Action {
+	case formActionCancel:
+		b.reply(req, f.texts.cancelled.For(req.From.Locale))
+		return FSMFinish, nil
+	case formActionBack:
+		session.Step = max(0, session.Step-1)
+		f.prompt(b, req, session.Step)
+		return formState, nil
+	case formActionSkip:
+		if !field.Optional {
+			return formState, nil
+		}
+	case formActionChoose:
+		if err := f.setValue(req, session, field, action.Value); err != nil {
+			return formState, err
+		}
+	default:
+		value := strings.TrimSpace(req.Command.Body)
+		if field.Kind == FormFieldChoice {
+			value = f.choiceValue(field, value, req.From.Locale)
+		}
+		if err := f.setValue(req, session, field, value); err != nil {
+			return formState, err
+		}
+	}
+
+	session.Step++
+	if session.Step < len(f.fields) {
+		f.prompt(b, req, session.Step)
+		return formState, nil
+	}
+	if err := f.onComplete(ctx, b, req, &session.Result); err != nil {
+		b.replyError(ctx, req, err)
+	}
+	return FSMFinish, nil
+}
+
+// choiceValue maps typed label of a choice to its value.
+func (f *Form[T]) choiceValue(field FormField, text string, locale string) string {
+	for _, c := range field.Choices {
+		if strings.EqualFold(c.Label.For(locale), text) {
+			return c.Value
+		}
+	}
+	return text
+}
+
+var (
+	invalidFormAnswer = LocalizedString{
+		"en": "Invalid answer: %s.",
+		"ru": "Неверный ответ: %s.",
+	}
+	errNotFormChoice = &valueError{LocalizedString{"en": "choose one of the options", "ru": "выберите один из вариантов"}}
+	errExpectedFile  = &valueError{LocalizedString{"en": "expected a file", "ru": "ожидается файл"}}
+)
+
+func (f *Form[T]) setValue(req *models.CommandRequest, session *FormSession[T], field FormField, value string) error {
+	target := reflect.ValueOf(&session.Result).Elem().Field(f.fieldIdx[session.Step])
+	converted := reflect.New(target.Type()).Elem()
+	var err error
+	switch {
+	case field.Kind == FormFieldFile:
+		err = setFileValue(req, converted)
+	case field.Kind == FormFieldChoice && !f.isChoice(field, value):
+		err = errNotFormChoice
+	default:
+		err = setArgValue(req, converted, value, false)
+	}
+	if err != nil {
+		var localized LocalizedError
+		if errors.As(err, &localized) {
+			return err
+		}
+		message := make(LocalizedString, len(invalidFormAnswer))
+		for locale, template := range invalidFormAnswer {
+			message[locale] = fmt.Sprintf(template, localizedReason(err, locale))
+		}
+		return NewLocalizedUserError(message)
+	}
+	for _, rule := range field.Rules {
+		if err := rule(converted.Interface()); err != nil {
+			return err
+		}
+	}
+	target.Set(converted)
+	return nil
+}
+
+func (f *Form[T]) isChoice(field FormField, value string) bool {
+	for _, c := range field.Choices {
+		if c.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	asyncFileType  = reflect.TypeOf(models.AsyncFile{})
+	attachmentType = reflect.TypeOf(models.Attachment{})
+)
+
+func setFileValue(req *models.CommandRequest, target reflect.Value) error {
+	switch target.Type() {
+	case asyncFileType:
+		files, err := req.ParsedAsyncFiles()
+		if err != nil || len(files) == 0 {
+			return errExpectedFile
+		}
+		target.Set(reflect.ValueOf(files[0]))
+	case attachmentType:
+		attachments, err := req.ParsedAttachments()
+		if err != nil || len(attachments) == 0 {
+			return errExpectedFile
+		}
+		target.Set(reflect.ValueOf(attachments[0]))
+	default:
+		return fmt.Errorf("unsupported file field type %s", target.Type())
+	}
+	return nil
+}
+
+func (f *Form[T]) prompt(b *Bot, req *models.CommandRequest, step int) {
+	field := f.fields[step]
+	locale := req.From.Locale
+	button := func(label LocalizedString, action string, value string) models.NDButton {
+		return models.NDButton{
+			Command: f.command,
+			Label:   label.For(locale),
+			Data:    formButtonData{Form: f.command, Action: action, Value: value},
+			Opts:    &models.NDButtonOpts{Silent: true},
+		}
+	}
+	var options []models.NDRequestOption
+	for _, c := range field.Choices {
+		options = append(options, models.WithNDBubbleRow(button(c.Label, formActionChoose, c.Value)))
+	}
+	controls := []models.NDButton{}
+	if step > 0 {
+		controls = append(controls, button(f.texts.back, formActionBack, ""))
+	}
+	if field.Optional {
+		controls = append(controls, button(f.texts.skip, formActionSkip, ""))
+	}
+	controls = append(controls, button(f.texts.cancel, formActionCancel, ""))
+	options = append(options, models.WithNDBubbleRow(controls...))
+	b.reply(req, field.Prompt.For(locale), options...)
+}
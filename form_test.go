@@ -0,0 +1,75 @@
+package botx
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-botx/botx/models"
+)
+
+type testFormResult struct {
+	Name string
+}
+
+func TestFormLetsOtherCommandsThrough(t *testing.T) {
+	b := newTestBot(t)
+	var helped int
+	var result *testFormResult
+	b.Handle("/help", func(ctx context.Context, b *Bot, req *models.CommandRequest) {
+		helped++
+	})
+	form, err := NewForm("/form", []FormField{FormText("Name", LocalizedString{"en": "Name?"})},
+		func(ctx context.Context, b *Bot, req *models.CommandRequest, r *testFormResult) error {
+			result = r
+			return nil
+		}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	form.Register(b)
+
+	from := newTestCommand("").From
+	send := func(body string, data any) {
+		req := newTestCommand(body)
+		req.From = from
+		req.Command.Data = json.RawMessage(`{}`)
+		if data != nil {
+			req.Command.Data, _ = json.Marshal(data)
+		}
+		b.executeCommand(req)
+	}
+	send("/form", nil)
+	send("/help", nil)
+	if helped != 1 {
+		t.Fatalf("/help handled %d times during form, want 1", helped)
+	}
+	send("/other", formButtonData{Form: "/other", Action: formActionChoose, Value: "x"})
+	send("bob", nil)
+	if result == nil || result.Name != "bob" {
+		t.Fatalf("form result = %+v, want Name bob", result)
+	}
+}
+
+func TestFormTypedCancel(t *testing.T) {
+	b := newTestBot(t)
+	completed := false
+	form, err := NewForm("/form", []FormField{FormText("Name", LocalizedString{"en": "Name?"})},
+		func(ctx context.Context, b *Bot, req *models.CommandRequest, r *testFormResult) error {
+			completed = true
+			return nil
+		}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	form.Register(b)
+	from := newTestCommand("").From
+	for _, body := range []string{"/form", "Cancel", "bob"} {
+		req := newTestCommand(body)
+		req.From = from
+		b.executeCommand(req)
+	}
+	if completed {
+		t.Fatal("form completed after typed cancel")
+	}
+}
@@ -0,0 +1,158 @@
+package botx
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-botx/botx/models"
+	"github.com/google/uuid"
+)
+
+// FSMFinish returned by state handler ends conversation.
+const FSMFinish = ""
+
+// FSMKey identifies conversation of a user in a chat.
+type FSMKey struct {
+	ChatId   uuid.UUID `json:"group_chat_id"`
+	UserHUID uuid.UUID `json:"user_huid"`
+}
+
+func FSMKeyOf(req *models.CommandRequest) FSMKey {
+	return FSMKey{ChatId: req.From.GroupChatId, UserHUID: req.From.UserHUID}
+}
+
+// FSMSession is a stored conversation state with its data.
+type FSMSession[D any] struct {
+	State string `json:"state"`
+	Data  D      `json:"data"`
+}
+
+// FSMStorage keeps conversation sessions. Implementations must be safe for concurrent use.
+type FSMStorage[D any] interface {
+	// Load returns nil session if there is none.
+	Load(ctx context.Context, key FSMKey) (*FSMSession[D], error)
+	Save(ctx context.Context, key FSMKey, session FSMSession[D]) error
+	Delete(ctx context.Context, key FSMKey) error
+}
+
+type memoryFSMStorage[D any] struct {
+	sessions *kvCache[FSMKey, FSMSession[D]]
+}
+
+// NewMemoryFSMStorage returns in-process storage forgetting sessions not
+// updated for ttl.
+func NewMemoryFSMStorage[D any](ttl time.Duration) FSMStorage[D] {
+	return &memoryFSMStorage[D]{sessions: newTTLCache[FSMKey, FSMSession[D]](ttl)}
+}
+
+func (s *memoryFSMStorage[D]) Load(_ context.Context, key FSMKey) (*FSMSession[D], error) {
+	if session, ok := s.sessions.Get(key); ok {
+		return &session, nil
+	}
+	return nil, nil
+}
+
+func (s *memoryFSMStorage[D]) Save(_ context.Context, key FSMKey, session FSMSession[D]) error {
+	s.sessions.Set(key, session)
+	return nil
+}
+
+func (s *memoryFSMStorage[D]) Delete(_ context.Context, key FSMKey) error {
+	s.sessions.Delete(key)
+	return nil
+}
+
+// FSMHandler handles message of a user in a state, returning next state or
+// FSMFinish. Changes made to data are saved with the next state. On error the
+// state is kept and error is replied like by HandleErrors.
+type FSMHandler[D any] func(ctx context.Context, b *Bot, req *models.CommandRequest, data *D) (next string, err error)
+
+// FSM runs multi-step conversations. Messages of users having an active session
+// are routed by Middleware to the handler of the session state, others go
+// through. Use WithOrderedCommands to keep messages of a user in order.
+type FSM[D any] struct {
+	storage  FSMStorage[D]
+	handlers map[string]FSMHandler[D]
+}
+
+const defaultFSMSessionTTL = time.Hour
+
+// NewFSM creates state machine keeping sessions in storage, in memory by default.
+func NewFSM[D any](storage ...FSMStorage[D]) *FSM[D] {
+	f := &FSM[D]{handlers: make(map[string]FSMHandler[D])}
+	if len(storage) > 0 && storage[0] != nil {
+		f.storage = storage[0]
+	} else {
+		f.storage = NewMemoryFSMStorage[D](defaultFSMSessionTTL)
+	}
+	return f
+}
+
+// On registers handler of state. Handlers must be registered before use.
+func (f *FSM[D]) On(state string, handler FSMHandler[D]) *FSM[D] {
+	if state == FSMFinish || handler == nil {
+		panic("botx: FSM state must have a name and a handler")
+	}
+	f.handlers[state] = handler
+	return f
+}
+
+// Start begins conversation in state, replacing active one.
+func (f *FSM[D]) Start(ctx context.Context, key FSMKey, state string, data D) error {
+	return f.storage.Save(ctx, key, FSMSession[D]{State: state, Data: data})
+}
+
+// Stop ends conversation.
+func (f *FSM[D]) Stop(ctx context.Context, key FSMKey) error {
+	return f.storage.Delete(ctx, key)
+}
+
+// Session returns active conversation or nil.
+func (f *FSM[D]) Session(ctx context.Context, key FSMKey) (*FSMSession[D], error) {
+	return f.storage.Load(ctx, key)
+}
+
+// Middleware dispatches messages of users with active session.
+func (f *FSM[D]) Middleware() CommandMiddleware {
+	return func(next CommandCallbackContextHandler) CommandCallbackContextHandler {
+		return func(ctx context.Context, b *Bot, req *models.CommandRequest) {
+			if !f.handle(ctx, b, req) {
+				next(ctx, b, req)
+			}
+		}
+	}
+}
+
+// handle runs state handler for req, returns false if user has no session.
+func (f *FSM[D]) handle(ctx context.Context, b *Bot, req *models.CommandRequest) bool {
+	key := FSMKeyOf(req)
+	session, err := f.storage.Load(ctx, key)
+	if err != nil {
+		b.logger.Error("failed to load FSM session", "sync_id", req.SyncId, "error", err)
+		return false
+	}
+	if session == nil {
+		return false
+	}
+	handler, ok := f.handlers[session.State]
+	if !ok {
+		b.logger.Error("no handler for FSM state, session dropped", "state", session.State, "sync_id", req.SyncId)
+		f.storage.Delete(ctx, key)
+		return false
+	}
+	next, err := handler(ctx, b, req, &session.Data)
+	if err != nil {
+		b.replyError(ctx, req, err)
+		return true
+	}
+	if next == FSMFinish {
+		err = f.storage.Delete(ctx, key)
+	} else {
+		session.State = next
+		err = f.storage.Save(ctx, key, *session)
+	}
+	if err != nil {
+		b.logger.Error("failed to save FSM session", "sync_id", req.SyncId, "error", err)
+	}
+	return true
+}
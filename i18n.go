@@ -0,0 +1,32 @@
+package botx
+
+import (
+	"strings"
+)
+
+const defaultLocale = "en"
+
+// LocalizedString holds translations of a user facing text keyed by language
+// code ("en", "ru", ...).
+type LocalizedString map[string]string
+
+// For returns text for the locale reported by client (e.g. "ru" or "ru-RU"),
+// falling back to English and then to any available translation.
+func (ls LocalizedString) For(locale string) string {
+	locale = strings.ToLower(locale)
+	if s, ok := ls[locale]; ok {
+		return s
+	}
+	if i := strings.IndexAny(locale, "-_"); i > 0 {
+		if s, ok := ls[locale[:i]]; ok {
+			return s
+		}
+	}
+	if s, ok := ls[defaultLocale]; ok {
+		return s
+	}
+	for _, s := range ls {
+		return s
+	}
+	return ""
+}
@@ -5,13 +5,18 @@ import (
 	"time"
 )
 
+// kvCache keeps entries until validTime of the cache, or the one passed to
+// SetFor and SetIfAbsentFor, passes since they were set.
 type kvCache[K comparable, V any] struct {
 	entries            map[K]V
 	entriesExpirations map[K]time.Time
 	validTime          time.Duration
+	lastPurge          time.Time
 	mu                 sync.RWMutex
 }
 
+// newKVCache returns cache that does not store validTime, so entries expire as
+// soon as they are set. Use newTTLCache for entries to be kept.
 func newKVCache[K comparable, V any](validTime time.Duration) *kvCache[K, V] {
 	return &kvCache[K, V]{
 		entries:            make(map[K]V),
@@ -19,22 +24,81 @@ func newKVCache[K comparable, V any](validTime time.Duration) *kvCache[K, V] {
 	}
 }
 
+// newTTLCache returns cache keeping entries for validTime and dropping expired
+// ones from time to time.
+func newTTLCache[K comparable, V any](validTime time.Duration) *kvCache[K, V] {
+	return &kvCache[K, V]{
+		entries:            make(map[K]V),
+		entriesExpirations: make(map[K]time.Time),
+		validTime:          validTime,
+		lastPurge:          time.Now(),
+	}
+}
+
 func (uc *kvCache[K, V]) Set(k K, v V) {
 	uc.mu.Lock()
 	defer uc.mu.Unlock()
+	uc.set(k, v, uc.validTime)
+}
+
+// SetFor is Set keeping the entry for validTime instead of the cache default.
+func (uc *kvCache[K, V]) SetFor(k K, v V, validTime time.Duration) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	uc.set(k, v, validTime)
+}
+
+// SetIfAbsent stores v unless there is a valid entry for k, returns whether v was stored.
+func (uc *kvCache[K, V]) SetIfAbsent(k K, v V) bool {
+	return uc.SetIfAbsentFor(k, v, uc.validTime)
+}
+
+// SetIfAbsentFor is SetIfAbsent keeping the entry for validTime instead of the cache default.
+func (uc *kvCache[K, V]) SetIfAbsentFor(k K, v V, validTime time.Duration) bool {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	if _, ok := uc.entries[k]; ok && !uc.expired(k) {
+		return false
+	}
+	uc.set(k, v, validTime)
+	return true
+}
+
+func (uc *kvCache[K, V]) Delete(k K) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	delete(uc.entries, k)
+	delete(uc.entriesExpirations, k)
+}
+
+func (uc *kvCache[K, V]) set(k K, v V, validTime time.Duration) {
+	now := time.Now()
 	uc.entries[k] = v
-	uc.entriesExpirations[k] = time.Now().Add(uc.validTime)
+	uc.entriesExpirations[k] = now.Add(validTime)
+	// drop expired entries from time to time so cache does not grow unbounded
+	if uc.validTime > 0 && now.Sub(uc.lastPurge) > uc.validTime {
+		uc.lastPurge = now
+		for k := range uc.entries {
+			if uc.expired(k) {
+				delete(uc.entries, k)
+				delete(uc.entriesExpirations, k)
+			}
+		}
+	}
+}
+
+func (uc *kvCache[K, V]) expired(k K) bool {
+	exp, ok := uc.entriesExpirations[k]
+	return !ok || exp.Before(time.Now())
 }
 
 func (uc *kvCache[K, V]) Get(k K) (v V, ok bool) {
 	uc.mu.RLock()
 	defer uc.mu.RUnlock()
 	v, ok = uc.entries[k]
-	if ok {
-		exp, okExp := uc.entriesExpirations[k]
-		if !okExp || exp.Before(time.Now()) {
-			ok = false
-		}
+	if ok && uc.expired(k) {
+		var zero V
+		return zero, false
 	}
 	return
 }
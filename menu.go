@@ -0,0 +1,194 @@
+package botx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/go-botx/botx/models"
+)
+
+// MenuItem is an entry of a menu shown as bubble button.
+type MenuItem struct {
+	Label string
+	Value string
+}
+
+// MenuSource returns items in [offset, offset+limit) and total number of items.
+type MenuSource func(ctx context.Context, b *Bot, req *models.CommandRequest, offset int, limit int) (items []MenuItem, total int, err error)
+
+// MenuSelectHandler is called with value of the item clicked by user.
+type MenuSelectHandler func(ctx context.Context, b *Bot, req *models.CommandRequest, value string) error
+
+// MenuItems is a MenuSource of a fixed list.
+func MenuItems(items ...MenuItem) MenuSource {
+	return func(_ context.Context, _ *Bot, _ *models.CommandRequest, offset int, limit int) ([]MenuItem, int, error) {
+		offset = min(offset, len(items))
+		return items[offset:min(offset+limit, len(items))], len(items), nil
+	}
+}
+
+type MenuOption func(m *Menu)
+
+// WithMenuPageSize sets number of items on a page, 10 by default.
+func WithMenuPageSize(n int) MenuOption {
+	return func(m *Menu) {
+		m.pageSize = n
+	}
+}
+
+// WithMenuColumns sets number of item buttons in a row, 1 by default.
+func WithMenuColumns(n int) MenuOption {
+	return func(m *Menu) {
+		m.columns = n
+	}
+}
+
+func WithMenuNavigation(prev LocalizedString, next LocalizedString) MenuOption {
+	return func(m *Menu) {
+		m.prev, m.next = prev, next
+	}
+}
+
+func WithMenuEmptyMessage(message LocalizedString) MenuOption {
+	return func(m *Menu) {
+		m.empty = message
+	}
+}
+
+const (
+	menuActionPage   = "page"
+	menuActionSelect = "select"
+)
+
+type menuButtonData struct {
+	Action string `json:"a"`
+	Page   int    `json:"p,omitempty"`
+	Value  string `json:"v,omitempty"`
+}
+
+// Menu shows items page by page as bubble buttons. Navigation buttons edit the
+// same message, page number is kept in button data.
+type Menu struct {
+	command  string
+	title    LocalizedString
+	source   MenuSource
+	onSelect MenuSelectHandler
+	pageSize int
+	columns  int
+	prev     LocalizedString
+	next     LocalizedString
+	empty    LocalizedString
+}
+
+// NewMenu creates menu shown with title by command. Buttons of the menu send
+// the same command with data.
+func NewMenu(command string, title LocalizedString, source MenuSource, onSelect MenuSelectHandler, options ...MenuOption) (*Menu, error) {
+	if source == nil || onSelect == nil {
+		return nil, errors.New("menu must have source and select handler")
+	}
+	m := &Menu{
+		command:  command,
+		title:    title,
+		source:   source,
+		onSelect: onSelect,
+		pageSize: 10,
+		columns:  1,
+		prev:     LocalizedString{"en": "« Prev", "ru": "« Назад"},
+		next:     LocalizedString{"en": "Next »", "ru": "Далее »"},
+		empty:    LocalizedString{"en": "Nothing to show.", "ru": "Список пуст."},
+	}
+	for _, opt := range options {
+		opt(m)
+	}
+	if m.pageSize <= 0 || m.columns <= 0 {
+		return nil, errors.New("menu page size and columns must be positive")
+	}
+	if rows := (m.pageSize + m.columns - 1) / m.columns; rows+1 > models.MaxButtonRows || m.columns > models.MaxButtonsInRow {
+		return nil, fmt.Errorf("menu page does not fit in %d rows of %d buttons", models.MaxButtonRows, models.MaxButtonsInRow)
+	}
+	return m, nil
+}
+
+// Register routes menu command to the menu.
+func (m *Menu) Register(r CommandRouter) {
+	r.Handle(m.command, m.handle)
+}
+
+// Show sends the first page of the menu to the chat req came from.
+func (m *Menu) Show(ctx context.Context, b *Bot, req *models.CommandRequest) {
+	buttons, total, err := m.page(ctx, b, req, 0)
+	if err != nil {
+		b.replyError(ctx, req, err)
+		return
+	}
+	if total == 0 {
+		b.reply(req, m.empty.For(req.From.Locale))
+		return
+	}
+	b.reply(req, m.title.For(req.From.Locale), models.WithNDButtons(buttons))
+}
+
+func (m *Menu) handle(ctx context.Context, b *Bot, req *models.CommandRequest) {
+	var data menuButtonData
+	if len(req.Command.Data) == 0 || json.Unmarshal(req.Command.Data, &data) != nil || data.Action == "" {
+		m.Show(ctx, b, req)
+		return
+	}
+	var err error
+	switch data.Action {
+	case menuActionPage:
+		var buttons *models.NDButtonsBuilder
+		if buttons, _, err = m.page(ctx, b, req, data.Page); err == nil {
+			err = b.EditMessage(req.From.GroupChatId, req.SourceSyncId, models.WithEditButtons(buttons))
+		}
+	case menuActionSelect:
+		err = m.onSelect(ctx, b, req, data.Value)
+	}
+	if err != nil {
+		b.replyError(ctx, req, err)
+	}
+}
+
+// page builds buttons of page n, n is clamped to existing pages.
+func (m *Menu) page(ctx context.Context, b *Bot, req *models.CommandRequest, n int) (*models.NDButtonsBuilder, int, error) {
+	// n comes from button data, keep offset and offset+limit from overflowing
+	n = min(max(n, 0), (math.MaxInt-m.pageSize)/m.pageSize)
+	items, total, err := m.source(ctx, b, req, n*m.pageSize, m.pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	pages := (total + m.pageSize - 1) / m.pageSize
+	if n >= pages && pages > 0 {
+		n = pages - 1
+		if items, total, err = m.source(ctx, b, req, n*m.pageSize, m.pageSize); err != nil {
+			return nil, 0, err
+		}
+	}
+	locale := req.From.Locale
+	buttons := models.NewBubbles()
+	for i := 0; i < len(items); i += m.columns {
+		var row []models.NDButton
+		for _, item := range items[i:min(i+m.columns, len(items))] {
+			row = append(row, models.NewCommandButton(item.Label, m.command, menuButtonData{Action: menuActionSelect, Value: item.Value}))
+		}
+		buttons.Row(row...)
+	}
+	if pages > 1 {
+		var nav []models.NDButton
+		if n > 0 {
+			nav = append(nav, models.NewCommandButton(m.prev.For(locale), m.command, menuButtonData{Action: menuActionPage, Page: n - 1}, models.WithButtonSilent()))
+		}
+		nav = append(nav, models.NDButton{
+			Label: fmt.Sprintf("%d/%d", n+1, pages),
+			Opts:  &models.NDButtonOpts{Handler: "client"},
+		})
+		if n < pages-1 {
+			nav = append(nav, models.NewCommandButton(m.next.For(locale), m.command, menuButtonData{Action: menuActionPage, Page: n + 1}, models.WithButtonSilent()))
+		}
+		buttons.Row(nav...)
+	}
+	return buttons, total, nil
+}
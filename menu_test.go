@@ -0,0 +1,23 @@
+package botx
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/go-botx/botx/models"
+)
+
+func TestMenuPageOutOfRange(t *testing.T) {
+	b := newTestBot(t)
+	m, err := NewMenu("/pick", LocalizedString{"en": "Pick"}, MenuItems(MenuItem{"A", "a"}, MenuItem{"B", "b"}, MenuItem{"C", "c"}),
+		func(context.Context, *Bot, *models.CommandRequest, string) error { return nil }, WithMenuPageSize(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int{-1, 5, 1000000000000000000, math.MaxInt} {
+		if _, total, err := m.page(context.Background(), b, newTestCommand("/pick"), n); err != nil || total != 3 {
+			t.Errorf("page(%d) = %d items, %v; want 3 items", n, total, err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package botx
+
+import (
+	"context"
+	"runtime/debug"
+	"slices"
+	"time"
+
+	"github.com/go-botx/botx/models"
+)
+
+// Recovery recovers handler panics and logs them with stack trace.
+func Recovery() CommandMiddleware {
+	return func(next CommandCallbackContextHandler) CommandCallbackContextHandler {
+		return func(ctx context.Context, b *Bot, req *models.CommandRequest) {
+			defer func() {
+				if r := recover(); r != nil {
+					b.logger.Error("command handler panicked",
+						"panic", r, "sync_id", req.SyncId, "body", req.Command.Body, "stack", string(debug.Stack()))
+				}
+			}()
+			next(ctx, b, req)
+		}
+	}
+}
+
+// Logging logs every command with its sender and handling time.
+func Logging() CommandMiddleware {
+	return func(next CommandCallbackContextHandler) CommandCallbackContextHandler {
+		return func(ctx context.Context, b *Bot, req *models.CommandRequest) {
+			started := time.Now()
+			next(ctx, b, req)
+			b.logger.Info("command handled",
+				"sync_id", req.SyncId,
+				"command", CommandName(req),
+				"body", req.Command.Body,
+				"user_huid", req.From.UserHUID,
+				"chat_id", req.From.GroupChatId,
+				"elapsed", time.Since(started))
+		}
+	}
+}
+
+// Filter passes commands for which allow returns true, others are dropped.
+// If reply is given, sender of a dropped command is notified.
+func Filter(allow func(ctx context.Context, b *Bot, req *models.CommandRequest) bool, reply ...LocalizedString) CommandMiddleware {
+	return func(next CommandCallbackContextHandler) CommandCallbackContextHandler {
+		return func(ctx context.Context, b *Bot, req *models.CommandRequest) {
+			if allow(ctx, b, req) {
+				next(ctx, b, req)
+				return
+			}
+			b.logger.Debug("command filtered out", "sync_id", req.SyncId, "command", CommandName(req))
+			if len(reply) > 0 {
+				b.reply(req, reply[0].For(req.From.Locale))
+			}
+		}
+	}
+}
+
+// AdminOnly passes commands from chat admins only.
+func AdminOnly(reply ...LocalizedString) CommandMiddleware {
+	return Filter(func(_ context.Context, _ *Bot, req *models.CommandRequest) bool {
+		return req.From.IsAdmin
+	}, reply...)
+}
+
+// ChatTypes passes commands sent from chats of given types only.
+func ChatTypes(chatTypes []models.ChatType, reply ...LocalizedString) CommandMiddleware {
+	return Filter(func(_ context.Context, _ *Bot, req *models.CommandRequest) bool {
+		return slices.Contains(chatTypes, req.From.ChatType)
+	}, reply...)
+}
@@ -26,7 +26,7 @@ type clientRequest struct {
 	needAuth          bool
 }
 
-func newGetRequest(rr string) ClientRequest {
+func newGetRequest(rr string) *clientRequest {
 	return &clientRequest{
 		method:            fiber.MethodGet,
 		relativeReference: rr,
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+type DeleteEventRequest struct {
+	ClientRequest `json:"-"`
+	SyncId        uuid.UUID `json:"sync_id"`
+}
+
+func NewDeleteEventRequest(syncId uuid.UUID) *DeleteEventRequest {
+	return &DeleteEventRequest{
+		ClientRequest: newPostRequest("/api/v3/botx/events/delete_event").
+			WithAuth().SetContentTypeJSONUTF8().SetBodyJSON(DeleteEventRequest{
+			SyncId: syncId,
+		}),
+	}
+}
+
+func (r *DeleteEventRequest) GetResponse(callFunc ClientApiCallFunc) (resp *SyncIdResponse, err error) {
+	clientResponse, err := parseClientResponseJson(callFunc(r))
+	if err != nil {
+		return
+	}
+	resp = &SyncIdResponse{clientResponseJson: clientResponse}
+	return
+}
+
+type EditEventRequest struct {
+	ClientRequest `json:"-"`
+	SyncId        uuid.UUID        `json:"sync_id"`
+	Payload       EditEventPayload `json:"payload"`
+}
+
+// EditEventPayload holds changed parts of the message, nil fields stay unchanged.
+type EditEventPayload struct {
+	Body     *string         `json:"body,omitempty"`
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+	Bubble   *NDButtons      `json:"bubble,omitempty"`
+	Keyboard *NDButtons      `json:"keyboard,omitempty"`
+	Opts     *EditEventOpts  `json:"opts,omitempty"`
+}
+
+type EditEventOpts struct {
+	ButtonsAutoAdjust bool `json:"buttons_auto_adjust"`
+}
+
+type EditEventOption func(p *EditEventPayload) error
+
+func NewEditEventRequest(syncId uuid.UUID, options ...EditEventOption) (*EditEventRequest, error) {
+	r := &EditEventRequest{SyncId: syncId}
+	for _, opt := range options {
+		if err := opt(&r.Payload); err != nil {
+			return nil, err
+		}
+	}
+	r.ClientRequest = newPostRequest("/api/v3/botx/events/edit_event").WithAuth().SetContentTypeJSONUTF8()
+	return r, nil
+}
+
+func (r *EditEventRequest) HasBody() bool {
+	return true
+}
+
+// Body marshals request when it is sent, so changes made after NewEditEventRequest are not lost.
+func (r *EditEventRequest) Body() []byte {
+	body, err := json.Marshal(r)
+	if err != nil {
+		panic(err)
+	}
+	return body
+}
+
+func (r *EditEventRequest) GetResponse(callFunc ClientApiCallFunc) (resp *SyncIdResponse, err error) {
+	clientResponse, err := parseClientResponseJson(callFunc(r))
+	if err != nil {
+		return
+	}
+	resp = &SyncIdResponse{clientResponseJson: clientResponse}
+	return
+}
+
+func WithEditBody(body string) EditEventOption {
+	return func(p *EditEventPayload) error {
+		p.Body = &body
+		return nil
+	}
+}
+
+func WithEditMetadata(metadata any) EditEventOption {
+	data, err := json.Marshal(metadata)
+	return func(p *EditEventPayload) error {
+		p.Metadata = data
+		return err
+	}
+}
+
+// WithEditBubble replaces all bubble rows, nil buttons remove them.
+func WithEditBubble(buttons NDButtons) EditEventOption {
+	return func(p *EditEventPayload) error {
+		if buttons == nil {
+			buttons = NDButtons{}
+		}
+		p.Bubble = &buttons
+		return nil
+	}
+}
+
+// WithEditKeyboard replaces all keyboard rows, nil buttons remove them.
+func WithEditKeyboard(buttons NDButtons) EditEventOption {
+	return func(p *EditEventPayload) error {
+		if buttons == nil {
+			buttons = NDButtons{}
+		}
+		p.Keyboard = &buttons
+		return nil
+	}
+}
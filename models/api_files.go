@@ -0,0 +1,18 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+type DownloadFileRequest struct {
+	ClientRequest `json:"-"`
+}
+
+// NewDownloadFileRequest describes file download, the response body is raw file content.
+func NewDownloadFileRequest(chatId uuid.UUID, fileId uuid.UUID, preview bool) *DownloadFileRequest {
+	return &DownloadFileRequest{
+		ClientRequest: newGetRequest(fmt.Sprintf("/api/v3/botx/files/download?group_chat_id=%s&file_id=%s&is_preview=%t", chatId, fileId, preview)).WithAuth(),
+	}
+}
@@ -31,10 +31,23 @@ func NewNDRequest(chatId uuid.UUID, body string, options ...NDRequestOption) (*N
 			return nil, err
 		}
 	}
-	ndr.ClientRequest = newPostRequest("/api/v4/botx/notifications/direct").WithAuth().SetContentTypeJSONUTF8().SetBodyJSON(ndr)
+	ndr.ClientRequest = newPostRequest("/api/v4/botx/notifications/direct").WithAuth().SetContentTypeJSONUTF8()
 	return ndr, nil
 }
 
+func (nd *NDRequest) HasBody() bool {
+	return true
+}
+
+// Body marshals request when it is sent, so changes made after NewNDRequest are not lost.
+func (nd *NDRequest) Body() []byte {
+	body, err := json.Marshal(nd)
+	if err != nil {
+		panic(err)
+	}
+	return body
+}
+
 func (nd *NDRequest) GetResponse(callFunc ClientApiCallFunc) (resp *SyncIdResponse, err error) {
 	clientResponse, err := parseClientResponseJson(callFunc(nd))
 	if err != nil {
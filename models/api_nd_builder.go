@@ -0,0 +1,131 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Limits checked by NDButtonsBuilder.
+const (
+	MaxButtonRows      = 25
+	MaxButtonsInRow    = 8
+	MaxButtonLabelSize = 128
+)
+
+// NDButtonsBuilder assembles bubble or keyboard buttons row by row:
+//
+//	models.NewBubbles().
+//		Row(models.NewCommandButton("Yes", "/answer", true), models.NewCommandButton("No", "/answer", false)).
+//		Row(models.NewLinkButton("Docs", "https://example.com")).
+//		AutoAdjust()
+type NDButtonsBuilder struct {
+	keyboard   bool
+	autoAdjust bool
+	rows       NDButtons
+}
+
+// NewBubbles starts buttons attached to the message.
+func NewBubbles() *NDButtonsBuilder {
+	return &NDButtonsBuilder{}
+}
+
+// NewKeyboard starts buttons shown in place of the chat keyboard.
+func NewKeyboard() *NDButtonsBuilder {
+	return &NDButtonsBuilder{keyboard: true}
+}
+
+// Row appends a row of buttons.
+func (bb *NDButtonsBuilder) Row(buttons ...NDButton) *NDButtonsBuilder {
+	bb.rows = append(bb.rows, NDButtonRow(buttons))
+	return bb
+}
+
+// AutoAdjust lets client rearrange buttons to fit the screen.
+func (bb *NDButtonsBuilder) AutoAdjust() *NDButtonsBuilder {
+	bb.autoAdjust = true
+	return bb
+}
+
+// Build validates and returns the rows.
+func (bb *NDButtonsBuilder) Build() (NDButtons, error) {
+	kind := "bubble"
+	if bb.keyboard {
+		kind = "keyboard"
+	}
+	if len(bb.rows) > MaxButtonRows {
+		return nil, fmt.Errorf("%s has %d rows, at most %d allowed", kind, len(bb.rows), MaxButtonRows)
+	}
+	for i, row := range bb.rows {
+		if len(row) == 0 {
+			return nil, fmt.Errorf("%s row %d is empty", kind, i)
+		}
+		if len(row) > MaxButtonsInRow {
+			return nil, fmt.Errorf("%s row %d has %d buttons, at most %d allowed", kind, i, len(row), MaxButtonsInRow)
+		}
+		for j, button := range row {
+			if err := button.validate(); err != nil {
+				return nil, fmt.Errorf("%s button %d:%d: %w", kind, i, j, err)
+			}
+		}
+	}
+	return bb.rows, nil
+}
+
+func (b *NDButton) validate() error {
+	if b.Label == "" {
+		return errors.New("label is empty")
+	}
+	if len([]rune(b.Label)) > MaxButtonLabelSize {
+		return fmt.Errorf("label is longer than %d characters", MaxButtonLabelSize)
+	}
+	if b.Opts != nil && b.Opts.HSize < 0 {
+		return errors.New("h_size is negative")
+	}
+	if b.Command == "" && (b.Opts == nil || b.Opts.Handler != "client") {
+		return errors.New("command is empty")
+	}
+	return nil
+}
+
+// WithNDButtons sets bubble or keyboard of the message built by bb.
+func WithNDButtons(bb *NDButtonsBuilder) NDRequestOption {
+	return func(ndr *NDRequest) error {
+		rows, err := bb.Build()
+		if err != nil {
+			return err
+		}
+		if bb.keyboard {
+			ndr.Notification.Keyboard = rows
+		} else {
+			ndr.Notification.Bubble = rows
+		}
+		if bb.autoAdjust {
+			ndr.Notification.Opts.ButtonsAutoAdjust = true
+		}
+		return nil
+	}
+}
+
+func WithNDKeyboardRow(buttons ...NDButton) NDRequestOption {
+	return func(ndr *NDRequest) error {
+		ndr.Notification.Keyboard = append(ndr.Notification.Keyboard, NDButtonRow(buttons))
+		return nil
+	}
+}
+
+// WithEditButtons replaces bubble or keyboard of the message with ones built by bb.
+func WithEditButtons(bb *NDButtonsBuilder) EditEventOption {
+	return func(p *EditEventPayload) error {
+		rows, err := bb.Build()
+		if err != nil {
+			return err
+		}
+		if bb.autoAdjust {
+			p.Opts = &EditEventOpts{ButtonsAutoAdjust: true}
+		}
+		if bb.keyboard {
+			return WithEditKeyboard(rows)(p)
+		}
+		return WithEditBubble(rows)(p)
+	}
+}
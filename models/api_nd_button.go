@@ -1,10 +1,21 @@
 package models
 
+import (
+	"github.com/google/uuid"
+)
+
 type NDButton struct {
 	Command string        `json:"command,omitempty"`
 	Label   string        `json:"label"`
 	Data    any           `json:"data,omitempty"`
 	Opts    *NDButtonOpts `json:"opts,omitempty"`
+
+	// BoundUserHUID limits clicks to one user when bot signs button data.
+	BoundUserHUID uuid.UUID `json:"-"`
+	// OneTime buttons accept the first click only, then their row is replaced
+	// with OneTimeLabel.
+	OneTime      bool   `json:"-"`
+	OneTimeLabel string `json:"-"`
 }
 
 type NDButtonOpts struct {
@@ -19,6 +30,9 @@ type NDButtonOpts struct {
 	Handler         string        `json:"handler,omitempty"`
 }
 
+// ButtonOneTimeUser is replaced with name of the user clicked one-time button.
+const ButtonOneTimeUser = "{user}"
+
 type NDButtonAlign string
 
 const (
@@ -43,6 +57,47 @@ func NewLinkButton(label string, url string, options ...NDButtonOption) NDButton
 	return b
 }
 
+// NewCommandButton creates button sending command with data to the bot when clicked.
+func NewCommandButton[T any](label string, command string, data T, options ...NDButtonOption) NDButton {
+	b := NDButton{
+		Command: command,
+		Label:   label,
+		Data:    data,
+		Opts:    &NDButtonOpts{},
+	}
+	for _, opt := range options {
+		opt(&b)
+	}
+	return b
+}
+
+// WithButtonBoundUser makes signed button clickable by the user only.
+func WithButtonBoundUser(userHUID uuid.UUID) NDButtonOption {
+	return func(b *NDButton) {
+		b.BoundUserHUID = userHUID
+	}
+}
+
+// WithButtonOneTime lets the button be clicked once. After the click its row is
+// replaced with doneLabel, "✓ <label>: {user}" by default. Clicks are kept in
+// memory unless bot has a store set by WithOneTimeButtonStore; the row is
+// replaced only by the bot process that sent the message and until restart.
+func WithButtonOneTime(doneLabel ...string) NDButtonOption {
+	return func(b *NDButton) {
+		b.OneTime = true
+		if len(doneLabel) > 0 {
+			b.OneTimeLabel = doneLabel[0]
+		}
+	}
+}
+
+// WithButtonSilent makes client send the command without showing it in the chat.
+func WithButtonSilent() NDButtonOption {
+	return func(b *NDButton) {
+		b.Opts.Silent = true
+	}
+}
+
 func WithButtonFontColor(color string) NDButtonOption {
 	return func(b *NDButton) {
 		b.Opts.FontColor = color
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// AsyncFile describes a file uploaded by user to the file service, its content
+// is downloaded separately (see Bot.OpenAsyncFile).
+type AsyncFile struct {
+	Type               AttachmentType `json:"type"`
+	File               string         `json:"file"`
+	FileId             uuid.UUID      `json:"file_id"`
+	FileName           string         `json:"file_name"`
+	FileSize           int64          `json:"file_size"`
+	FileMimeType       string         `json:"file_mime_type"`
+	FileHash           string         `json:"file_hash"`
+	FileEncryptionAlgo string         `json:"file_encryption_algo,omitempty"`
+	FilePreview        string         `json:"file_preview,omitempty"`
+	FilePreviewWidth   int            `json:"file_preview_width,omitempty"`
+	FilePreviewHeight  int            `json:"file_preview_height,omitempty"`
+	ChunkSize          int            `json:"chunk_size,omitempty"`
+	Duration           int            `json:"duration,omitempty"`
+	Caption            string         `json:"caption,omitempty"`
+}
+
+// HasPreview reports whether file service keeps a preview for the file.
+func (f *AsyncFile) HasPreview() bool {
+	return f.FilePreview != ""
+}
+
+// ParsedAsyncFiles decodes r.AsyncFiles into typed models.
+func (r *CommandRequest) ParsedAsyncFiles() ([]AsyncFile, error) {
+	files := make([]AsyncFile, 0, len(r.AsyncFiles))
+	for _, raw := range r.AsyncFiles {
+		var f AsyncFile
+		if err := json.Unmarshal(raw, &f); err != nil {
+			return files, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
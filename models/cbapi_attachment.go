@@ -0,0 +1,131 @@
+package models
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type AttachmentType string
+
+const (
+	AttachmentTypeImage    = AttachmentType("image")
+	AttachmentTypeVideo    = AttachmentType("video")
+	AttachmentTypeDocument = AttachmentType("document")
+	AttachmentTypeVoice    = AttachmentType("voice")
+	AttachmentTypeLocation = AttachmentType("location")
+	AttachmentTypeContact  = AttachmentType("contact")
+	AttachmentTypeLink     = AttachmentType("link")
+)
+
+// Attachment is an item of CommandRequest.Attachments. Exactly one of File,
+// Location, Contact and Link is set depending on Type; attachments of unknown
+// types keep only Type and Data.
+type Attachment struct {
+	Type AttachmentType  `json:"type"`
+	Data json.RawMessage `json:"data"`
+
+	File     *AttachmentFile     `json:"-"`
+	Location *AttachmentLocation `json:"-"`
+	Contact  *AttachmentContact  `json:"-"`
+	Link     *AttachmentLink     `json:"-"`
+}
+
+// AttachmentFile is an image, video, document or voice message sent inline.
+// Content is a data URL ("data:image/png;base64,...").
+type AttachmentFile struct {
+	FileName string `json:"file_name"`
+	Content  string `json:"content"`
+	Duration int    `json:"duration,omitempty"` // seconds, video and voice only
+
+	MimeType string `json:"-"`
+	Size     int64  `json:"-"` // decoded content size in bytes
+
+	payload string
+}
+
+type AttachmentLocation struct {
+	Name      string `json:"location_name"`
+	Address   string `json:"location_address"`
+	Latitude  string `json:"location_lat"`
+	Longitude string `json:"location_lng"`
+}
+
+type AttachmentContact struct {
+	Name string `json:"contact_name"`
+}
+
+type AttachmentLink struct {
+	URL     string `json:"url"`
+	Preview string `json:"url_preview"`
+	Text    string `json:"url_text"`
+	Title   string `json:"url_title"`
+}
+
+func (a *Attachment) UnmarshalJSON(data []byte) error {
+	type plain Attachment
+	if err := json.Unmarshal(data, (*plain)(a)); err != nil {
+		return err
+	}
+	var err error
+	switch a.Type {
+	case AttachmentTypeImage, AttachmentTypeVideo, AttachmentTypeDocument, AttachmentTypeVoice:
+		a.File = &AttachmentFile{}
+		if err = json.Unmarshal(a.Data, a.File); err == nil {
+			err = a.File.parseContent()
+		}
+	case AttachmentTypeLocation:
+		a.Location = &AttachmentLocation{}
+		err = json.Unmarshal(a.Data, a.Location)
+	case AttachmentTypeContact:
+		a.Contact = &AttachmentContact{}
+		err = json.Unmarshal(a.Data, a.Contact)
+	case AttachmentTypeLink:
+		a.Link = &AttachmentLink{}
+		err = json.Unmarshal(a.Data, a.Link)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse %s attachment: %w", a.Type, err)
+	}
+	return nil
+}
+
+func (f *AttachmentFile) parseContent() error {
+	if f.Content == "" {
+		return nil
+	}
+	header, payload, ok := strings.Cut(f.Content, ",")
+	if !ok || !strings.HasPrefix(header, "data:") || !strings.HasSuffix(header, ";base64") {
+		return errors.New("content is not a base64 data URL")
+	}
+	f.MimeType = strings.TrimSuffix(strings.TrimPrefix(header, "data:"), ";base64")
+	f.payload = payload
+	f.Size = int64(base64.StdEncoding.DecodedLen(len(payload)) - strings.Count(payload[max(0, len(payload)-2):], "="))
+	return nil
+}
+
+// Reader returns decoder of file content.
+func (f *AttachmentFile) Reader() io.Reader {
+	return base64.NewDecoder(base64.StdEncoding, strings.NewReader(f.payload))
+}
+
+// Bytes returns decoded file content.
+func (f *AttachmentFile) Bytes() ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, f.Size))
+	_, err := io.Copy(buf, f.Reader())
+	return buf.Bytes(), err
+}
+
+// ParsedAttachments decodes r.Attachments into typed models.
+func (r *CommandRequest) ParsedAttachments() ([]Attachment, error) {
+	var attachments []Attachment
+	if len(r.Attachments) == 0 || string(r.Attachments) == "null" {
+		return attachments, nil
+	}
+	err := json.Unmarshal(r.Attachments, &attachments)
+	return attachments, err
+}
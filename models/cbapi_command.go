@@ -2,6 +2,7 @@ package models
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -34,26 +35,75 @@ type CommandData struct {
 }
 
 type CommandFrom struct {
-	UserHUID    uuid.UUID `json:"user_huid"`
-	GroupChatId uuid.UUID `json:"group_chat_id"`
-	ChatType    ChatType  `json:"chat_type"`
-	///	ad_login [String] (Default: null) - логин юзера который отправил команду
-	/// ad_domain [String] (Default: null) - домен юзера который отправил команду
-	/// username [String] (Default: null) - имя юзера который отправил команду
-	IsAdmin bool `json:"is_admin"`
-	/// is_creator [Boolean] (Default: null) - является ли юзер создателем чата
-	/// manufacturer [String] (Default: null) - имя бренда производителя
-	/// device [String] (Default: null) - название девайса
-	/// device_software [String] (Default: null) - ОС девайса
-	/// device_meta [Object] (Default: null)
-	///    pushes [Boolean] - разрешение приложению на отправку пушей
-	///    timezone [String] - таймзона пользователя
-	///    permissions [Object] - различные разрешения приложения (использование микрофона, камеры и т.д.)
-	/// platform [String] (Default: null) - название клиентской платформы (web|android|ios|desktop)
-	/// platform_package_id [String] (Default: null) - идентификатор пакета с данными приложения и устройства
-	/// app_version [String] (Default: null) - версия приложения Express
-	Locale string `json:"locale"`
-	/// host [String] - имя хоста с которого пришла команда
+	UserHUID          uuid.UUID   `json:"user_huid"`
+	GroupChatId       uuid.UUID   `json:"group_chat_id"`
+	ChatType          ChatType    `json:"chat_type"`
+	ADLogin           string      `json:"ad_login"`
+	ADDomain          string      `json:"ad_domain"`
+	Username          string      `json:"username"`
+	IsAdmin           bool        `json:"is_admin"`
+	IsCreator         bool        `json:"is_creator"`
+	Manufacturer      string      `json:"manufacturer"`
+	Device            string      `json:"device"`
+	DeviceSoftware    string      `json:"device_software"`
+	DeviceMeta        *DeviceMeta `json:"device_meta"`
+	Platform          Platform    `json:"platform"`
+	PlatformPackageId string      `json:"platform_package_id"`
+	AppVersion        string      `json:"app_version"`
+	Locale            string      `json:"locale"`
+	Host              string      `json:"host"`
+}
+
+// Timezone returns user timezone reported by client device, or UTC when unknown.
+func (f *CommandFrom) Timezone() *time.Location {
+	if f.DeviceMeta != nil && f.DeviceMeta.Timezone != nil {
+		return f.DeviceMeta.Timezone
+	}
+	return time.UTC
+}
+
+type Platform string
+
+const (
+	PlatformWeb     = Platform("web")
+	PlatformAndroid = Platform("android")
+	PlatformIOS     = Platform("ios")
+	PlatformDesktop = Platform("desktop")
+)
+
+// IsMobile reports whether command was sent from a phone or tablet client.
+func (p Platform) IsMobile() bool {
+	return p == PlatformAndroid || p == PlatformIOS
+}
+
+type DeviceMeta struct {
+	Pushes       bool              `json:"pushes"`
+	TimezoneName string            `json:"timezone"`
+	Timezone     *time.Location    `json:"-"` // nil if TimezoneName is unknown to the system tz database
+	Permissions  DevicePermissions `json:"permissions"`
+}
+
+func (dm *DeviceMeta) UnmarshalJSON(data []byte) error {
+	type plain DeviceMeta
+	if err := json.Unmarshal(data, (*plain)(dm)); err != nil {
+		return err
+	}
+	dm.Timezone = nil
+	if dm.TimezoneName != "" {
+		if loc, err := time.LoadLocation(dm.TimezoneName); err == nil {
+			dm.Timezone = loc
+		}
+	}
+	return nil
+}
+
+// DevicePermissions lists application permissions granted on device (microphone, camera, ...).
+type DevicePermissions map[string]any
+
+// Granted reports whether permission is present and enabled.
+func (dp DevicePermissions) Granted(name string) bool {
+	v, _ := dp[name].(bool)
+	return v
 }
 
 type CommandResponse struct {
@@ -0,0 +1,163 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EntityType string
+
+const (
+	EntityTypeMention = EntityType("mention")
+	EntityTypeForward = EntityType("forward")
+	EntityTypeReply   = EntityType("reply")
+)
+
+// Entity is an item of CommandRequest.Entities. One of Mention, Forward and
+// Reply is set depending on Type; entities of unknown types keep only Type and Data.
+type Entity struct {
+	Type EntityType      `json:"type"`
+	Data json.RawMessage `json:"data"`
+
+	Mention *Mention `json:"-"`
+	Forward *Forward `json:"-"`
+	Reply   *Reply   `json:"-"`
+}
+
+type Mention struct {
+	MentionType NDMentionType `json:"mention_type"`
+	MentionId   uuid.UUID     `json:"mention_id"`
+	MentionData MentionData   `json:"mention_data"`
+}
+
+type MentionData struct {
+	UserHUID uuid.UUID `json:"user_huid"`
+	ChatId   uuid.UUID `json:"group_chat_id"`
+	Name     string    `json:"name"`
+	ConnType string    `json:"conn_type"`
+}
+
+// Placeholder returns text that stands for the mention in message body.
+func (m *Mention) Placeholder() string {
+	return "@{mention:" + m.MentionId.String() + "}"
+}
+
+// IsUser reports whether mention refers to a user or a contact.
+func (m *Mention) IsUser() bool {
+	return m.MentionType == NDMentionTypeUser || m.MentionType == NDMentionTypeContact
+}
+
+type Forward struct {
+	ChatId           uuid.UUID `json:"group_chat_id"`
+	SenderHUID       uuid.UUID `json:"sender_huid"`
+	ForwardType      ChatType  `json:"forward_type"`
+	SourceChatName   string    `json:"source_chat_name"`
+	SourceSyncId     uuid.UUID `json:"source_sync_id"`
+	SourceInsertedAt time.Time `json:"source_inserted_at"`
+}
+
+type Reply struct {
+	SourceSyncId   uuid.UUID   `json:"source_sync_id"`
+	SenderHUID     uuid.UUID   `json:"sender"`
+	Body           string      `json:"body"`
+	Mentions       []Mention   `json:"mentions"`
+	Attachment     *Attachment `json:"attachment"`
+	ReplyType      ChatType    `json:"reply_type"`
+	SourceChatId   uuid.UUID   `json:"source_group_chat_id"`
+	SourceChatName string      `json:"source_chat_name"`
+}
+
+func (e *Entity) UnmarshalJSON(data []byte) error {
+	type plain Entity
+	if err := json.Unmarshal(data, (*plain)(e)); err != nil {
+		return err
+	}
+	var err error
+	switch e.Type {
+	case EntityTypeMention:
+		e.Mention = &Mention{}
+		err = json.Unmarshal(e.Data, e.Mention)
+	case EntityTypeForward:
+		e.Forward = &Forward{}
+		err = json.Unmarshal(e.Data, e.Forward)
+	case EntityTypeReply:
+		e.Reply = &Reply{}
+		err = json.Unmarshal(e.Data, e.Reply)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse %s entity: %w", e.Type, err)
+	}
+	return nil
+}
+
+// ParsedEntities decodes r.Entities into typed models. Entities that fail to
+// parse are skipped, the first error is returned along with the rest.
+func (r *CommandRequest) ParsedEntities() (entities []Entity, err error) {
+	for _, raw := range r.Entities {
+		var e Entity
+		if errE := json.Unmarshal(raw, &e); errE != nil {
+			if err == nil {
+				err = errE
+			}
+			continue
+		}
+		entities = append(entities, e)
+	}
+	return entities, err
+}
+
+// Mentions returns all mentions of the message.
+func (r *CommandRequest) Mentions() (mentions []Mention) {
+	entities, _ := r.ParsedEntities()
+	for _, e := range entities {
+		if e.Mention != nil {
+			mentions = append(mentions, *e.Mention)
+		}
+	}
+	return mentions
+}
+
+// MentionedUsers returns users and contacts mentioned in the message.
+func (r *CommandRequest) MentionedUsers() (users []User) {
+	for _, m := range r.Mentions() {
+		if m.IsUser() {
+			users = append(users, User{UserHUID: m.MentionData.UserHUID, Name: m.MentionData.Name})
+		}
+	}
+	return users
+}
+
+// IsBotMentioned reports whether the receiving bot is mentioned in the message.
+func (r *CommandRequest) IsBotMentioned() bool {
+	for _, m := range r.Mentions() {
+		if m.IsUser() && m.MentionData.UserHUID == r.BotId {
+			return true
+		}
+	}
+	return false
+}
+
+// ReplyTo returns the message this one replies to, or nil.
+func (r *CommandRequest) ReplyTo() *Reply {
+	entities, _ := r.ParsedEntities()
+	for _, e := range entities {
+		if e.Reply != nil {
+			return e.Reply
+		}
+	}
+	return nil
+}
+
+// ForwardedFrom returns origin of a forwarded message, or nil.
+func (r *CommandRequest) ForwardedFrom() *Forward {
+	entities, _ := r.ParsedEntities()
+	for _, e := range entities {
+		if e.Forward != nil {
+			return e.Forward
+		}
+	}
+	return nil
+}
@@ -22,6 +22,7 @@ type ncbManager struct {
 	messages    map[uuid.UUID]ncbmStoredMessage
 	closeChan   chan struct{}
 	cleanupDone chan struct{}
+	stopOnce    sync.Once
 }
 
 func newNCBManager(storeTime time.Duration) *ncbManager {
@@ -37,8 +38,10 @@ func newNCBManager(storeTime time.Duration) *ncbManager {
 }
 
 func (ncbm *ncbManager) stop() {
-	close(ncbm.closeChan)
-	<-ncbm.cleanupDone
+	ncbm.stopOnce.Do(func() {
+		close(ncbm.closeChan)
+		<-ncbm.cleanupDone
+	})
 }
 
 func (ncbm *ncbManager) periodicCleanup() {
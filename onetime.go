@@ -0,0 +1,173 @@
+package botx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-botx/botx/models"
+	"github.com/google/uuid"
+)
+
+// oneTimeButtonsTTL is how long clicks on one-time buttons are remembered.
+const oneTimeButtonsTTL = 7 * 24 * time.Hour
+
+var errButtonUsed = errors.New("button is already used")
+
+var defaultUsedButtonReply = LocalizedString{
+	"en": "This button has already been used.",
+	"ru": "Эта кнопка уже использована.",
+}
+
+// oneTimeButtonData replaces data of one-time buttons, it is stored and signed
+// further when enabled.
+type oneTimeButtonData struct {
+	Once *oneTimeRef     `json:"botx_once"`
+	Data json.RawMessage `json:"d,omitempty"`
+}
+
+// oneTimeRef is position of one-time button in the message.
+type oneTimeRef struct {
+	Keyboard bool `json:"k,omitempty"`
+	Row      int  `json:"r"`
+	Column   int  `json:"c"`
+}
+
+type oneTimeMessage struct {
+	chatId   uuid.UUID
+	bubble   models.NDButtons
+	keyboard models.NDButtons
+}
+
+// oneTimeButtons tracks sent messages with one-time buttons and rows already
+// clicked. Messages are kept in memory of the process that sent them, claims
+// are kept in DedupStore set by WithOneTimeButtonStore, in memory by default.
+type oneTimeButtons struct {
+	mtx      sync.Mutex
+	messages *kvCache[uuid.UUID, *oneTimeMessage]
+	claims   DedupStore
+}
+
+func newOneTimeButtons() *oneTimeButtons {
+	return &oneTimeButtons{
+		messages: newTTLCache[uuid.UUID, *oneTimeMessage](oneTimeButtonsTTL),
+		claims:   NewMemoryDedupStore(oneTimeButtonsTTL),
+	}
+}
+
+func hasOneTimeButtons(rows ...models.NDButtons) bool {
+	for _, buttons := range rows {
+		for _, row := range buttons {
+			for _, button := range row {
+				if button.OneTime {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// remember keeps encoded buttons of sent message to update them after click.
+func (o *oneTimeButtons) remember(syncId uuid.UUID, message *models.NDRequest) {
+	if !hasOneTimeButtons(message.Notification.Bubble, message.Notification.Keyboard) {
+		return
+	}
+	o.messages.Set(syncId, &oneTimeMessage{
+		chatId:   message.ChatId,
+		bubble:   copyButtons(message.Notification.Bubble),
+		keyboard: copyButtons(message.Notification.Keyboard),
+	})
+}
+
+// claimId identifies row of the message in DedupStore.
+func (ref oneTimeRef) claimId(syncId uuid.UUID) uuid.UUID {
+	return uuid.NewSHA1(syncId, []byte(fmt.Sprintf("botx-one-time:%t:%d", ref.Keyboard, ref.Row)))
+}
+
+// claim reports whether this is the first click on the row of the message.
+func (o *oneTimeButtons) claim(ctx context.Context, syncId uuid.UUID, ref oneTimeRef) (bool, error) {
+	return o.claims.MarkSeen(ctx, ref.claimId(syncId), oneTimeButtonsTTL)
+}
+
+// release lets the row be clicked again when the click was refused.
+func (o *oneTimeButtons) release(ctx context.Context, syncId uuid.UUID, ref oneTimeRef) error {
+	return o.claims.Forget(ctx, ref.claimId(syncId))
+}
+
+// unwrapOneTimeButton restores data of one-time button in req and returns its
+// position, or nil for other buttons.
+func unwrapOneTimeButton(req *models.CommandRequest) (*oneTimeRef, error) {
+	var once oneTimeButtonData
+	if json.Unmarshal(req.Command.Data, &once) != nil || once.Once == nil {
+		return nil, nil
+	}
+	if req.SourceSyncId == uuid.Nil {
+		return nil, errButtonInvalid
+	}
+	req.Command.Data = once.Data
+	return once.Once, nil
+}
+
+// claimOneTimeButton rejects all clicks on the row except the first accepted
+// one. The returned release function must be called if the click is refused
+// afterwards, accept when its command is taken for handling.
+func (b *Bot) claimOneTimeButton(ctx context.Context, req *models.CommandRequest, ref *oneTimeRef) (accept func(), release func(), err error) {
+	if ref == nil {
+		return func() {}, func() {}, nil
+	}
+	first, err := b.oneTime.claim(ctx, req.SourceSyncId, *ref)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to claim one-time button: %w", err)
+	}
+	if !first {
+		return nil, nil, errButtonUsed
+	}
+	accept = func() {
+		user := req.From.Username
+		if user == "" {
+			user = req.From.UserHUID.String()
+		}
+		go b.completeOneTimeButton(req.SourceSyncId, user, *ref)
+	}
+	release = func() {
+		if err := b.oneTime.release(ctx, req.SourceSyncId, *ref); err != nil {
+			b.logger.Error("failed to release one-time button", "source_sync_id", req.SourceSyncId, "error", err)
+		}
+	}
+	return accept, release, nil
+}
+
+// completeOneTimeButton replaces the clicked row with done label of the button.
+func (b *Bot) completeOneTimeButton(syncId uuid.UUID, user string, ref oneTimeRef) {
+	b.oneTime.mtx.Lock()
+	defer b.oneTime.mtx.Unlock()
+	message, ok := b.oneTime.messages.Get(syncId)
+	if !ok {
+		b.logger.Debug("one-time button message is unknown, not updated", "source_sync_id", syncId)
+		return
+	}
+	buttons, option := message.bubble, models.WithEditBubble
+	if ref.Keyboard {
+		buttons, option = message.keyboard, models.WithEditKeyboard
+	}
+	if ref.Row >= len(buttons) || ref.Column >= len(buttons[ref.Row]) {
+		return
+	}
+	button := buttons[ref.Row][ref.Column]
+	label := button.OneTimeLabel
+	if label == "" {
+		label = "✓ " + button.Label + ": " + models.ButtonOneTimeUser
+	}
+	buttons[ref.Row] = models.NDButtonRow{{
+		Label: strings.ReplaceAll(label, models.ButtonOneTimeUser, user),
+		Opts:  &models.NDButtonOpts{Handler: "client"},
+	}}
+	if err := b.EditMessage(message.chatId, syncId, option(buttons)); err != nil {
+		b.logger.Error("failed to update one-time button message", "source_sync_id", syncId, "error", err)
+	}
+}
@@ -0,0 +1,103 @@
+package botx
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-botx/botx/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// oneTimeClick returns click on the first button of a message with one-time
+// buttons, sent by a new user.
+func oneTimeClick(t *testing.T, b *Bot) *models.CommandRequest {
+	t.Helper()
+	chatId := uuid.New()
+	return clickOn(t, b, chatId, models.NewCommandButton("Approve", "/approve", 1, models.WithButtonOneTime()))
+}
+
+func TestOneTimeButtonClaimedOnce(t *testing.T) {
+	b := newTestBot(t)
+	req := oneTimeClick(t, b)
+	ref, err := unwrapOneTimeButton(req)
+	if err != nil || ref == nil {
+		t.Fatalf("unwrapOneTimeButton() = %v, %v", ref, err)
+	}
+	if string(req.Command.Data) != "1" {
+		t.Errorf("data = %s, want 1", req.Command.Data)
+	}
+
+	var wg sync.WaitGroup
+	var claimed, used atomic.Int32
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			switch _, _, err := b.claimOneTimeButton(context.Background(), req, ref); err {
+			case nil:
+				claimed.Add(1)
+			case errButtonUsed:
+				used.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if claimed.Load() != 1 || used.Load() != 19 {
+		t.Fatalf("%d clicks claimed and %d rejected, want 1 and 19", claimed.Load(), used.Load())
+	}
+}
+
+func TestOneTimeButtonAcceptedClick(t *testing.T) {
+	var handled atomic.Int32
+	b := newTestBot(t, WithCommandContextHandler(func(ctx context.Context, b *Bot, req *models.CommandRequest) {
+		handled.Add(1)
+	}))
+	defer b.Shutdown(context.Background())
+	click := oneTimeClick(t, b)
+	if code := postCommand(t, b, click); code != fiber.StatusAccepted {
+		t.Fatalf("command callback status = %d", code)
+	}
+	retry := *click
+	retry.SyncId = uuid.New()
+	if code := postCommand(t, b, &retry); code != fiber.StatusAccepted {
+		t.Fatalf("command callback status = %d", code)
+	}
+	b.handlersWG.Wait()
+	if handled.Load() != 1 {
+		t.Fatalf("one-time button handled %d times, want 1", handled.Load())
+	}
+}
+
+func TestOneTimeButtonReleasedOnRefusal(t *testing.T) {
+	var handled atomic.Int32
+	b := newTestBot(t, WithRateLimit(RateLimit{Every: time.Hour, Burst: 1}), WithCommandContextHandler(func(ctx context.Context, b *Bot, req *models.CommandRequest) {
+		handled.Add(1)
+	}))
+	defer b.Shutdown(context.Background())
+	click := oneTimeClick(t, b)
+	plain := newTestCommand("hello")
+	plain.From = click.From
+	plain.Command.Data = json.RawMessage(`{}`)
+	if code := postCommand(t, b, plain); code != fiber.StatusAccepted {
+		t.Fatalf("command callback status = %d", code)
+	}
+	// refused by rate limiter
+	if code := postCommand(t, b, click); code != fiber.StatusAccepted {
+		t.Fatalf("command callback status = %d", code)
+	}
+	b.handlersWG.Wait()
+	if handled.Load() != 1 {
+		t.Fatalf("handled %d commands, want only the plain one", handled.Load())
+	}
+
+	req := *click
+	ref, _ := unwrapOneTimeButton(&req)
+	if first, err := b.oneTime.claim(context.Background(), req.SourceSyncId, *ref); !first || err != nil {
+		t.Fatalf("claim after refused click = %v, %v, want it released", first, err)
+	}
+}
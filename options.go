@@ -1,9 +1,15 @@
 package botx
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
+	"log/slog"
+	"net/http"
+	"time"
 
+	"github.com/go-botx/botx/models"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 )
 
@@ -22,13 +28,279 @@ func WithStatusHandler(handler StatusCallbackHandler) Option {
 func WithCommandHandler(handler CommandCallbackHandler) Option {
 	return func(b *Bot) error {
 		if handler != nil {
-			b.commandCallbackHandler = handler
+			b.commandCallbackHandler = func(_ context.Context, b *Bot, req *models.CommandRequest) {
+				handler(b, req)
+			}
 			return nil
 		}
 		return errors.New("CommandCallbackHandler is nil")
 	}
 }
 
+func WithCommandContextHandler(handler CommandCallbackContextHandler) Option {
+	return func(b *Bot) error {
+		if handler != nil {
+			b.commandCallbackHandler = handler
+			return nil
+		}
+		return errors.New("CommandCallbackContextHandler is nil")
+	}
+}
+
+// WithAsyncFileMaxSize limits size of files downloaded by Bot.OpenAsyncFile, 0 means no limit.
+func WithAsyncFileMaxSize(maxSize int64) Option {
+	return func(b *Bot) error {
+		if maxSize < 0 {
+			return errors.New("async file max size must not be negative")
+		}
+		b.asyncFileMaxSize = maxSize
+		return nil
+	}
+}
+
+// WithHTTPClient sets client used for file service requests.
+func WithHTTPClient(client *http.Client) Option {
+	return func(b *Bot) error {
+		if client != nil {
+			b.httpClient = client
+			return nil
+		}
+		return errors.New("http.Client is nil")
+	}
+}
+
+// WithCommandWorkers handles commands on a pool of workers goroutines fed by a
+// queue of queueSize commands. Commands received while the queue is full are
+// refused with status message set by WithCommandQueueFullMessage.
+func WithCommandWorkers(workers int, queueSize int) Option {
+	return func(b *Bot) error {
+		if workers <= 0 || queueSize < 0 {
+			return errors.New("command workers number must be positive and queue size must not be negative")
+		}
+		b.commandWorkers = workers
+		b.commandQueueSize = queueSize
+		return nil
+	}
+}
+
+// WithOrderedCommands makes commands with the same key (e.g. OrderByChat) to be
+// handled sequentially in order of arrival, while commands with different keys
+// are still handled in parallel. At most queueSize commands wait per key, the
+// rest are refused like on full worker queue.
+func WithOrderedCommands(key CommandOrderKey, queueSize int) Option {
+	return func(b *Bot) error {
+		if key == nil {
+			return errors.New("CommandOrderKey is nil")
+		}
+		if queueSize <= 0 {
+			return errors.New("ordered commands queue size must be positive")
+		}
+		b.commandOrder = newOrderedCommands(key, queueSize)
+		return nil
+	}
+}
+
+// WithCommandWAL persists accepted commands to a write-ahead log in dir before
+// acknowledging them. Commands whose handlers did not finish before a crash,
+// and commands still queued when Shutdown timed out, are handled again on the
+// first FiberApp call, so routes registered after New are in place by then.
+// Handlers interrupted by Shutdown timeout are not run again.
+func WithCommandWAL(dir string) Option {
+	return func(b *Bot) error {
+		wal, err := openCommandWAL(dir)
+		if err != nil {
+			return fmt.Errorf("failed to open command WAL: %w", err)
+		}
+		b.wal = wal
+		return nil
+	}
+}
+
+// WithCommandTimeout cancels command handler context after timeout. Timed out
+// handlers are logged and, if reply is given, user is notified.
+func WithCommandTimeout(timeout time.Duration, reply ...LocalizedString) Option {
+	return func(b *Bot) error {
+		if timeout <= 0 {
+			return errors.New("command timeout must be positive")
+		}
+		b.commandTimeout = timeout
+		if len(reply) > 0 {
+			b.commandTimeoutReply = reply[0]
+		}
+		return nil
+	}
+}
+
+// WithCommandWatchdog reports handlers running longer than threshold with
+// their command body and sync id, by default to the logger.
+func WithCommandWatchdog(threshold time.Duration, hook ...SlowCommandHook) Option {
+	return func(b *Bot) error {
+		if threshold <= 0 {
+			return errors.New("watchdog threshold must be positive")
+		}
+		b.watchdogThreshold = threshold
+		if len(hook) > 0 {
+			b.slowCommandHook = hook[0]
+		}
+		return nil
+	}
+}
+
+func WithCommandQueueFullMessage(statusMessage string) Option {
+	return func(b *Bot) error {
+		b.queueFullMessage = statusMessage
+		return nil
+	}
+}
+
+// WithCommandDeduplication drops commands with sync id seen within ttl, answering
+// them as accepted without calling handler. Ids are kept in memory unless store
+// is given.
+func WithCommandDeduplication(ttl time.Duration, store ...DedupStore) Option {
+	return func(b *Bot) error {
+		if ttl <= 0 {
+			return errors.New("deduplication ttl must be positive")
+		}
+		b.dedupTTL = ttl
+		if len(store) > 0 && store[0] != nil {
+			b.dedupStore = store[0]
+		} else {
+			b.dedupStore = NewMemoryDedupStore(ttl)
+		}
+		return nil
+	}
+}
+
+func WithDuplicateCommandHook(hook DuplicateCommandHook) Option {
+	return func(b *Bot) error {
+		if hook != nil {
+			b.duplicateCommandHook = hook
+			return nil
+		}
+		return errors.New("DuplicateCommandHook is nil")
+	}
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	return func(b *Bot) error {
+		if logger != nil {
+			b.logger = logger
+			return nil
+		}
+		return errors.New("logger is nil")
+	}
+}
+
+// WithCommandErrorHandler is like WithCommandContextHandler for handler returning error.
+func WithCommandErrorHandler(handler CommandCallbackErrorHandler) Option {
+	return func(b *Bot) error {
+		if handler != nil {
+			b.commandCallbackHandler = HandleErrors(handler)
+			return nil
+		}
+		return errors.New("CommandCallbackErrorHandler is nil")
+	}
+}
+
+// WithErrorReplyTemplates sets templates of replies sent by HandleErrors for
+// user facing and internal errors. Templates may contain ErrorReplyMessage and
+// ErrorReplySyncId placeholders, nil keeps default template.
+func WithErrorReplyTemplates(userError LocalizedString, internalError LocalizedString) Option {
+	return func(b *Bot) error {
+		if userError != nil {
+			b.userErrorReply = userError
+		}
+		if internalError != nil {
+			b.internalErrorReply = internalError
+		}
+		return nil
+	}
+}
+
+// WithACL restricts bot usage to senders allowed by acl.
+func WithACL(acl ACL) Option {
+	return func(b *Bot) error {
+		b.SetACL(&acl)
+		return nil
+	}
+}
+
+// WithACLFile loads ACL from JSON file, which is reread by Bot.ReloadACL.
+func WithACLFile(path string) Option {
+	return func(b *Bot) error {
+		b.aclFile = path
+		return b.ReloadACL()
+	}
+}
+
+// WithRateLimit limits rate of commands per user. Commands over the limit are
+// dropped before handling and the user is asked to wait.
+func WithRateLimit(limit RateLimit) Option {
+	return func(b *Bot) error {
+		if limit.Every <= 0 || limit.Burst <= 0 {
+			return errors.New("rate limit interval and burst must be positive")
+		}
+		b.rateLimiter = newRateLimiter(limit)
+		return nil
+	}
+}
+
+// WithButtonSigning signs data of buttons in sent messages and verifies it on
+// click before the command reaches handlers. Forged, expired or foreign clicks
+// are rejected with a reply.
+func WithButtonSigning(signing ButtonSigning) Option {
+	return func(b *Bot) error {
+		if len(signing.Key) == 0 {
+			signing.Key = []byte(b.account.SecretKey)
+		}
+		if signing.TTL < 0 {
+			return errors.New("button signing TTL must not be negative")
+		}
+		if signing.InvalidReply == nil {
+			signing.InvalidReply = defaultInvalidButtonReply
+		}
+		if signing.ExpiredReply == nil {
+			signing.ExpiredReply = defaultExpiredButtonReply
+		}
+		if signing.ForeignReply == nil {
+			signing.ForeignReply = defaultForeignButtonReply
+		}
+		b.buttonSigning = &signing
+		return nil
+	}
+}
+
+// WithButtonDataStore replaces data of buttons in sent messages with a short
+// token and resolves it on click before the command reaches handlers. Data is
+// kept in memory unless store is given; clicks after ttl are rejected as expired.
+func WithButtonDataStore(ttl time.Duration, store ...ButtonDataStore) Option {
+	return func(b *Bot) error {
+		if ttl <= 0 {
+			return errors.New("button data ttl must be positive")
+		}
+		b.buttonStoreTTL = ttl
+		if len(store) > 0 && store[0] != nil {
+			b.buttonStore = store[0]
+		} else {
+			b.buttonStore = NewMemoryButtonDataStore(ttl)
+		}
+		return nil
+	}
+}
+
+// WithOneTimeButtonStore keeps clicks on one-time buttons in store, so they stay
+// rejected after restart and across bot replicas. Updating the clicked message
+// is done by the process which sent it, until it is restarted.
+func WithOneTimeButtonStore(store DedupStore) Option {
+	return func(b *Bot) error {
+		if store == nil {
+			return errors.New("one-time button store is nil")
+		}
+		b.oneTime.claims = store
+		return nil
+	}
+}
+
 func WithRecoverUnauthorized() Option {
 	return func(b *Bot) error {
 		b.recoverUnauthorized = true
@@ -0,0 +1,127 @@
+package botx
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-botx/botx/models"
+)
+
+// RateLimitRetryAfter is a placeholder of rate limit reply replaced with
+// number of seconds until next command is accepted.
+const RateLimitRetryAfter = "{retry_after}"
+
+// RateLimit describes token bucket of commands per user: Burst commands at
+// once, then one command per Every.
+type RateLimit struct {
+	Every        time.Duration
+	Burst        int
+	PerCommand   bool            // separate bucket for every command name
+	ExemptAdmins bool            // do not limit chat admins
+	Reply        LocalizedString // sent once when limit is hit, default is used if nil
+}
+
+var defaultRateLimitReply = LocalizedString{
+	"en": "Too many requests, please try again in " + RateLimitRetryAfter + " s.",
+	"ru": "Слишком много запросов, попробуйте через " + RateLimitRetryAfter + " с.",
+}
+
+type tokenBucket struct {
+	tokens   float64
+	updated  time.Time
+	notified bool
+}
+
+type rateLimiter struct {
+	RateLimit
+	mtx       sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	if limit.Reply == nil {
+		limit.Reply = defaultRateLimitReply
+	}
+	return &rateLimiter{
+		RateLimit: limit,
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// take spends a token of req sender. If there is none, returns time to wait
+// and whether the sender must be notified (first refusal since last success).
+func (rl *rateLimiter) take(req *models.CommandRequest) (ok bool, retryAfter time.Duration, notify bool) {
+	if rl.ExemptAdmins && req.From.IsAdmin {
+		return true, 0, false
+	}
+	key := req.From.UserHUID.String()
+	if rl.PerCommand {
+		key += " " + CommandName(req)
+	}
+	now := time.Now()
+
+	rl.mtx.Lock()
+	defer rl.mtx.Unlock()
+	rl.sweep(now)
+	bucket, exists := rl.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: float64(rl.Burst), updated: now}
+		rl.buckets[key] = bucket
+	}
+	rl.refill(bucket, now)
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		bucket.notified = false
+		return true, 0, false
+	}
+	retryAfter = time.Duration((1 - bucket.tokens) * float64(rl.Every))
+	notify = !bucket.notified
+	bucket.notified = true
+	return false, retryAfter, notify
+}
+
+func (rl *rateLimiter) refill(bucket *tokenBucket, now time.Time) {
+	bucket.tokens = math.Min(float64(rl.Burst), bucket.tokens+float64(now.Sub(bucket.updated))/float64(rl.Every))
+	bucket.updated = now
+}
+
+// sweep forgets full buckets, they are equal to new ones.
+func (rl *rateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < rl.Every*time.Duration(rl.Burst) {
+		return
+	}
+	rl.lastSweep = now
+	for key, bucket := range rl.buckets {
+		rl.refill(bucket, now)
+		if bucket.tokens >= float64(rl.Burst) {
+			delete(rl.buckets, key)
+		}
+	}
+}
+
+// isRateLimited reports whether req exceeds the limit, notifying the sender.
+func (b *Bot) isRateLimited(req *models.CommandRequest) bool {
+	if b.rateLimiter == nil {
+		return false
+	}
+	ok, retryAfter, notify := b.rateLimiter.take(req)
+	if ok {
+		return false
+	}
+	b.logger.Debug("command rate limited", "sync_id", req.SyncId, "user_huid", req.From.UserHUID)
+	if notify {
+		seconds := strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))
+		text := strings.ReplaceAll(b.rateLimiter.Reply.For(req.From.Locale), RateLimitRetryAfter, seconds)
+		go func() {
+			if _, err := b.Reply(req, text); err != nil {
+				b.logger.Error("failed to send rate limit reply", "sync_id", req.SyncId, "error", err)
+			}
+		}()
+	}
+	return true
+}
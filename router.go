@@ -0,0 +1,125 @@
+package botx
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/go-botx/botx/models"
+)
+
+// CommandMiddleware wraps command handler with cross-cutting logic.
+type CommandMiddleware func(next CommandCallbackContextHandler) CommandCallbackContextHandler
+
+type route struct {
+	router  *Router
+	handler CommandCallbackContextHandler
+}
+
+// Router dispatches commands to handlers by the first word of command body
+// ("/help"). Commands without a route go to the handler set by WithCommandHandler.
+// Groups share routes of their parent, adding their own middlewares.
+type Router struct {
+	parent      *Router
+	root        *Router
+	mtx         sync.RWMutex
+	middlewares []CommandMiddleware
+	routes      map[string]route // used in root only
+}
+
+func newRouter() *Router {
+	r := &Router{routes: make(map[string]route)}
+	r.root = r
+	return r
+}
+
+// Use appends middlewares applied to all handlers of the router and its groups.
+func (r *Router) Use(middlewares ...CommandMiddleware) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.middlewares = append(r.middlewares, middlewares...)
+}
+
+// Group returns a router sharing routes with r with extra middlewares.
+func (r *Router) Group(middlewares ...CommandMiddleware) *Router {
+	return &Router{
+		parent:      r,
+		root:        r.root,
+		middlewares: append([]CommandMiddleware{}, middlewares...),
+	}
+}
+
+// Handle registers handler for command, replacing previously registered one.
+func (r *Router) Handle(command string, handler CommandCallbackContextHandler) {
+	if handler == nil {
+		panic("botx: nil handler for command " + command)
+	}
+	r.root.mtx.Lock()
+	defer r.root.mtx.Unlock()
+	r.root.routes[strings.ToLower(command)] = route{router: r, handler: handler}
+}
+
+func (r *Router) hasRoute(command string) bool {
+	r.root.mtx.RLock()
+	defer r.root.mtx.RUnlock()
+	_, ok := r.root.routes[strings.ToLower(command)]
+	return ok
+}
+
+func (r *Router) hasRoutes() bool {
+	r.root.mtx.RLock()
+	defer r.root.mtx.RUnlock()
+	return len(r.root.routes) > 0
+}
+
+// resolve returns handler for req wrapped with middlewares, or fallback wrapped
+// with root middlewares if there is no route. Middlewares are run even if there
+// is no fallback, as they may handle the command themselves.
+func (r *Router) resolve(req *models.CommandRequest, fallback CommandCallbackContextHandler) CommandCallbackContextHandler {
+	r.root.mtx.RLock()
+	rt, ok := r.root.routes[CommandName(req)]
+	r.root.mtx.RUnlock()
+	if !ok {
+		if fallback == nil {
+			fallback = unhandledCommand
+		}
+		rt = route{router: r.root, handler: fallback}
+	}
+	return rt.router.wrap(rt.handler)
+}
+
+func unhandledCommand(_ context.Context, b *Bot, req *models.CommandRequest) {
+	b.logger.Debug("no handler for command", "sync_id", req.SyncId, "command", CommandName(req))
+}
+
+func (r *Router) wrap(handler CommandCallbackContextHandler) CommandCallbackContextHandler {
+	for rt := r; rt != nil; rt = rt.parent {
+		rt.mtx.RLock()
+		middlewares := rt.middlewares
+		rt.mtx.RUnlock()
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			handler = middlewares[i](handler)
+		}
+	}
+	return handler
+}
+
+// CommandName returns lowercased first word of command body.
+func CommandName(req *models.CommandRequest) string {
+	return strings.ToLower(firstWord(req.Command.Body))
+}
+
+// Use appends middlewares applied to every command handler.
+func (b *Bot) Use(middlewares ...CommandMiddleware) {
+	b.router.Use(middlewares...)
+}
+
+// Handle registers handler for command ("/help").
+func (b *Bot) Handle(command string, handler CommandCallbackContextHandler) {
+	b.router.Handle(command, handler)
+}
+
+// Group returns router to register commands sharing middlewares.
+func (b *Bot) Group(middlewares ...CommandMiddleware) *Router {
+	return b.router.Group(middlewares...)
+}
@@ -0,0 +1,87 @@
+package botx
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/go-botx/botx/models"
+	"github.com/google/uuid"
+)
+
+const redacted = "[REDACTED]"
+
+// Secret is a value entered by user in response to AskSecret. It is redacted
+// when printed or logged, use Reveal to get the value.
+type Secret struct {
+	value string
+}
+
+func (s Secret) Reveal() string {
+	return s.value
+}
+
+func (s Secret) String() string {
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return redacted
+}
+
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(redacted)
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(redacted), nil
+}
+
+type SecretOption func(o *secretOptions)
+
+type secretOptions struct {
+	deletePrompt bool
+	ndOptions    []models.NDRequestOption
+}
+
+// WithSecretPromptDeleted deletes the prompt message once secret is received.
+func WithSecretPromptDeleted() SecretOption {
+	return func(o *secretOptions) {
+		o.deletePrompt = true
+	}
+}
+
+// WithSecretPromptOptions applies options to the prompt message.
+func WithSecretPromptOptions(options ...models.NDRequestOption) SecretOption {
+	return func(o *secretOptions) {
+		o.ndOptions = append(o.ndOptions, options...)
+	}
+}
+
+// AskSecret sends prompt with silent response enabled, so the answer is hidden
+// in chat, and returns the next message of the user as Secret. The answer is
+// not passed to handlers and middlewares and is removed from the request
+// before HTTP service debug logging.
+func (b *Bot) AskSecret(ctx context.Context, chatId uuid.UUID, userHUID uuid.UUID, prompt string, options ...SecretOption) (Secret, error) {
+	var opts secretOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+	message, err := models.NewNDRequest(chatId, prompt, opts.ndOptions...)
+	if err != nil {
+		return Secret{}, err
+	}
+	message.Notification.Opts.SilentResponse = true
+
+	answer, syncId, err := b.askMessage(ctx, userHUID, message, true)
+	if opts.deletePrompt && syncId != uuid.Nil {
+		if errD := b.DeleteMessage(syncId); errD != nil {
+			b.logger.Error("failed to delete secret prompt", "sync_id", syncId, "error", errD)
+		}
+	}
+	if err != nil {
+		return Secret{}, err
+	}
+	secret := Secret{value: answer.Request.Command.Body}
+	answer.Request.Command.Body = ""
+	return secret, nil
+}
@@ -0,0 +1,299 @@
+package botx
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-botx/botx/models"
+	"github.com/google/uuid"
+)
+
+const (
+	walFileName = "commands.wal"
+	// log is rewritten with pending commands only once it has that many
+	// records and at least half of them are finished
+	walCompactRecords = 1000
+)
+
+const (
+	walOpAccept = "accept"
+	walOpDone   = "done"
+)
+
+type walRecord struct {
+	Op      string                 `json:"op"`
+	SyncId  uuid.UUID              `json:"sync_id"`
+	Command *models.CommandRequest `json:"command,omitempty"`
+}
+
+// commandWAL is an append-only log of accepted commands. A command is written
+// before the callback is acknowledged and marked done when its handler returns,
+// so commands lost on crash or restart are found and replayed on next start.
+// On graceful shutdown commands whose handlers have started are marked done as
+// well, only commands never started are replayed then.
+type commandWAL struct {
+	mtx     sync.Mutex
+	path    string
+	file    *os.File // nil when closed
+	pending map[uuid.UUID]*walCommand
+	seq     uint64
+	records int
+	replay  []*models.CommandRequest
+}
+
+type walCommand struct {
+	seq     uint64
+	req     *models.CommandRequest
+	started bool
+}
+
+func openCommandWAL(dir string) (*commandWAL, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, walFileName)
+	replay, err := readCommandWAL(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &commandWAL{
+		path:    path,
+		pending: make(map[uuid.UUID]*walCommand),
+		replay:  replay,
+	}
+	for _, req := range replay {
+		w.seq++
+		w.pending[req.SyncId] = &walCommand{seq: w.seq, req: req}
+	}
+	if err = w.compact(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// compact rewrites log with pending commands only.
+func (w *commandWAL) compact() error {
+	pending := make([]*walCommand, 0, len(w.pending))
+	for _, cmd := range w.pending {
+		pending = append(pending, cmd)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].seq < pending[j].seq })
+
+	tmpPath := w.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if w.file != nil {
+		w.file.Close()
+	}
+	w.file, w.records = tmp, 0
+	for _, cmd := range pending {
+		if err = w.write(walRecord{Op: walOpAccept, SyncId: cmd.req.SyncId, Command: cmd.req}); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	tmp.Close()
+	w.file = nil
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(tmpPath, w.path); err != nil {
+		return err
+	}
+	w.file, err = os.OpenFile(w.path, os.O_APPEND|os.O_WRONLY, 0o600)
+	return err
+}
+
+// readCommandWAL returns accepted but not done commands in order of acceptance.
+func readCommandWAL(path string) ([]*models.CommandRequest, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var order []uuid.UUID
+	accepted := map[uuid.UUID]*models.CommandRequest{}
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// incomplete last line is a record interrupted by crash
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var rec walRecord
+		if json.Unmarshal(line, &rec) != nil {
+			continue
+		}
+		switch rec.Op {
+		case walOpAccept:
+			if rec.Command != nil {
+				order = append(order, rec.SyncId)
+				accepted[rec.SyncId] = rec.Command
+			}
+		case walOpDone:
+			delete(accepted, rec.SyncId)
+		}
+	}
+	var replay []*models.CommandRequest
+	for _, syncId := range order {
+		if req, ok := accepted[syncId]; ok {
+			replay = append(replay, req)
+			delete(accepted, syncId)
+		}
+	}
+	return replay, nil
+}
+
+func (w *commandWAL) write(rec walRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = w.file.Write(append(data, '\n'))
+	w.records++
+	return err
+}
+
+// accept durably stores req before it is acknowledged.
+func (w *commandWAL) accept(req *models.CommandRequest) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.file == nil {
+		return os.ErrClosed
+	}
+	if err := w.write(walRecord{Op: walOpAccept, SyncId: req.SyncId, Command: req}); err != nil {
+		return err
+	}
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+	w.seq++
+	w.pending[req.SyncId] = &walCommand{seq: w.seq, req: req}
+	return nil
+}
+
+// start reports whether handler of the command may run, it may not once the
+// log is closed, as the command could not be marked done then.
+func (w *commandWAL) start(syncId uuid.UUID) bool {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.file == nil {
+		return false
+	}
+	if cmd, ok := w.pending[syncId]; ok {
+		cmd.started = true
+	}
+	return true
+}
+
+func (w *commandWAL) done(syncId uuid.UUID) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if _, ok := w.pending[syncId]; !ok || w.file == nil {
+		// handlers finishing after close are marked done by close
+		return nil
+	}
+	delete(w.pending, syncId)
+	if w.records >= walCompactRecords && w.records >= 2*len(w.pending) {
+		return w.compact()
+	}
+	return w.write(walRecord{Op: walOpDone, SyncId: syncId})
+}
+
+// takeReplay returns commands left unfinished by previous run, only once.
+func (w *commandWAL) takeReplay() []*models.CommandRequest {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	replay := w.replay
+	w.replay = nil
+	return replay
+}
+
+func (w *commandWAL) close() error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	// started handlers may still run, they must not run again on next start
+	var err error
+	for syncId, cmd := range w.pending {
+		if cmd.started {
+			if err = w.write(walRecord{Op: walOpDone, SyncId: syncId}); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = w.file.Sync()
+	}
+	if errC := w.file.Close(); errC != nil && err == nil {
+		err = errC
+	}
+	w.file = nil
+	return err
+}
+
+// commandStarted reports whether handler of req may run.
+func (b *Bot) commandStarted(req *models.CommandRequest) bool {
+	if b.wal == nil {
+		return true
+	}
+	return b.wal.start(req.SyncId)
+}
+
+func (b *Bot) commandDone(req *models.CommandRequest) {
+	if b.wal == nil {
+		return
+	}
+	if err := b.wal.done(req.SyncId); err != nil {
+		b.logger.Error("failed to mark command done in WAL", "sync_id", req.SyncId, "error", err)
+	}
+}
+
+// replayCommands resubmits commands left unfinished by previous run.
+func (b *Bot) replayCommands() {
+	if b.wal == nil {
+		return
+	}
+	replay := b.wal.takeReplay()
+	if len(replay) == 0 {
+		return
+	}
+	b.logger.Info("replaying unfinished commands", "count", len(replay))
+	go func() {
+		for _, req := range replay {
+			if b.dedupStore != nil {
+				b.dedupStore.MarkSeen(b.ctx, req.SyncId, b.dedupTTL)
+			}
+			for {
+				err := b.submitCommand(req)
+				if err == nil {
+					break
+				}
+				if err != errCommandQueueFull {
+					return
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+		}
+	}()
+}
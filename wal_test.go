@@ -0,0 +1,178 @@
+package botx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-botx/botx/models"
+	"github.com/google/uuid"
+)
+
+func syncIds(reqs []*models.CommandRequest) []uuid.UUID {
+	var ids []uuid.UUID
+	for _, req := range reqs {
+		ids = append(ids, req.SyncId)
+	}
+	return ids
+}
+
+func TestCommandWALReplaysUnfinished(t *testing.T) {
+	dir := t.TempDir()
+	w, err := openCommandWAL(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second, third := newTestCommand("/a"), newTestCommand("/b"), newTestCommand("/c")
+	for _, req := range []*models.CommandRequest{first, second, third} {
+		if err := w.accept(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.done(second.SyncId); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.close(); err != nil {
+		t.Fatal(err)
+	}
+	// record interrupted by crash
+	f, err := os.OpenFile(filepath.Join(dir, walFileName), os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"op":"accept","sync_id":"`)
+	f.Close()
+
+	w, err = openCommandWAL(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.close()
+	replay := w.takeReplay()
+	if got := syncIds(replay); len(got) != 2 || got[0] != first.SyncId || got[1] != third.SyncId {
+		t.Fatalf("replay = %v, want [%s %s]", got, first.SyncId, third.SyncId)
+	}
+	if replay[0].Command.Body != "/a" {
+		t.Errorf("replayed body = %q, want /a", replay[0].Command.Body)
+	}
+	if w.takeReplay() != nil {
+		t.Error("replay is returned twice")
+	}
+	if w.records != 2 {
+		t.Errorf("compacted log has %d records, want 2", w.records)
+	}
+}
+
+func TestCommandWALTruncatesWhenIdle(t *testing.T) {
+	w, err := openCommandWAL(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.close()
+	// accept and done are two records, the last done sees walCompactRecords
+	for i := 0; i <= walCompactRecords/2; i++ {
+		req := newTestCommand("/a")
+		if err := w.accept(req); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.done(req.SyncId); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info, err := w.file.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != 0 || w.records != 0 {
+		t.Errorf("log has %d records of %d bytes with no pending commands, want it truncated", w.records, info.Size())
+	}
+}
+
+func TestCommandWALCompactsWithPending(t *testing.T) {
+	dir := t.TempDir()
+	w, err := openCommandWAL(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := newTestCommand("/kept")
+	if err := w.accept(kept); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i <= walCompactRecords/2; i++ {
+		req := newTestCommand("/a")
+		if err := w.accept(req); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.done(req.SyncId); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if w.records >= walCompactRecords {
+		t.Errorf("log has %d records, want it compacted", w.records)
+	}
+	if err := w.close(); err != nil {
+		t.Fatal(err)
+	}
+	w, err = openCommandWAL(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.close()
+	if got := syncIds(w.takeReplay()); len(got) != 1 || got[0] != kept.SyncId {
+		t.Fatalf("replay = %v, want [%s]", got, kept.SyncId)
+	}
+}
+
+func TestShutdownTimeoutReplaysNotStartedCommands(t *testing.T) {
+	dir := t.TempDir()
+	started := make(chan struct{})
+	var runs atomic.Int32
+	b := newTestBot(t, WithCommandWAL(dir), WithCommandWorkers(1, 5), WithCommandContextHandler(func(ctx context.Context, b *Bot, req *models.CommandRequest) {
+		runs.Add(1)
+		if req.Command.Body == "/deploy" {
+			close(started)
+			<-ctx.Done()
+		}
+	}))
+	interrupted, queued := newTestCommand("/deploy"), newTestCommand("/pay")
+	for _, req := range []*models.CommandRequest{interrupted, queued} {
+		if err := b.wal.accept(req); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.submitCommand(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	b.Shutdown(ctx)
+	// let the worker pick queued command after shutdown
+	time.Sleep(50 * time.Millisecond)
+	if got := runs.Load(); got != 1 {
+		t.Fatalf("%d handlers run before restart, want 1", got)
+	}
+
+	replayed := make(chan *models.CommandRequest, 2)
+	b = newTestBot(t, WithCommandWAL(dir), WithCommandContextHandler(func(ctx context.Context, b *Bot, req *models.CommandRequest) {
+		replayed <- req
+	}))
+	defer b.Shutdown(context.Background())
+	b.FiberApp()
+	select {
+	case got := <-replayed:
+		if got.SyncId != queued.SyncId {
+			t.Fatalf("replayed %s, want %s", got.Command.Body, queued.Command.Body)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("queued command is not replayed")
+	}
+	select {
+	case got := <-replayed:
+		t.Fatalf("interrupted command %s is replayed", got.Command.Body)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
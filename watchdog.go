@@ -0,0 +1,73 @@
+package botx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-botx/botx/models"
+)
+
+// SlowCommandHook is called by watchdog for handlers running longer than threshold.
+type SlowCommandHook func(b *Bot, req *models.CommandRequest, elapsed time.Duration)
+
+// commandContext returns context for handling req, limited by WithCommandTimeout.
+func (b *Bot) commandContext() (context.Context, context.CancelFunc) {
+	if b.commandTimeout > 0 {
+		return context.WithTimeout(b.ctx, b.commandTimeout)
+	}
+	return context.WithCancel(b.ctx)
+}
+
+// watchCommand reports handler of req running longer than watchdog threshold
+// and handler timeout until returned stop function is called.
+func (b *Bot) watchCommand(ctx context.Context, req *models.CommandRequest) (stop func()) {
+	if b.watchdogThreshold <= 0 && b.commandTimeout <= 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+	started := time.Now()
+	go func() {
+		var slow <-chan time.Time
+		if b.watchdogThreshold > 0 {
+			timer := time.NewTimer(b.watchdogThreshold)
+			defer timer.Stop()
+			slow = timer.C
+		}
+		for {
+			select {
+			case <-done:
+				return
+			case <-slow:
+				slow = nil
+				b.reportSlowCommand(req, time.Since(started))
+			case <-ctx.Done():
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					b.reportCommandTimeout(req)
+				}
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+func (b *Bot) reportSlowCommand(req *models.CommandRequest, elapsed time.Duration) {
+	if b.slowCommandHook != nil {
+		b.slowCommandHook(b, req, elapsed)
+		return
+	}
+	b.logger.Warn("command handler is running too long",
+		"sync_id", req.SyncId, "body", req.Command.Body, "elapsed", elapsed)
+}
+
+func (b *Bot) reportCommandTimeout(req *models.CommandRequest) {
+	b.logger.Error("command handler timed out",
+		"sync_id", req.SyncId, "body", req.Command.Body, "timeout", b.commandTimeout)
+	if b.commandTimeoutReply == nil {
+		return
+	}
+	if _, err := b.Reply(req, b.commandTimeoutReply.For(req.From.Locale)); err != nil {
+		b.logger.Error("failed to send timeout reply", "sync_id", req.SyncId, "error", err)
+	}
+}